@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// apiHandler serves the JSON API under /api/v1/: status, peers, peers/<name>
+// and protocols. The ETag and Last-Modified headers are derived from the
+// time of the refresh that last changed the data, bird's server time
+// excepted, so clients polling with If-None-Match or If-Modified-Since get
+// 304 Not Modified until the next one does.
+type apiHandler struct {
+	h *BirdBGPHandler
+}
+
+func (a apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	snapshot := a.h.current()
+
+	var body interface{}
+	switch path := strings.TrimPrefix(r.URL.Path, "/api/v1/"); {
+	case path == "status":
+		body = snapshot.status
+	case path == "peers":
+		body = snapshot.protocols
+		if snapshot.protocols == nil {
+			body = []ProtocolBGPStatus{}
+		}
+	case strings.HasPrefix(path, "peers/"):
+		name := strings.TrimPrefix(path, "peers/")
+		for _, proto := range snapshot.protocols {
+			if proto.Name == name {
+				body = proto
+				break
+			}
+		}
+		if body == nil && !snapshot.updated.IsZero() {
+			apiError(w, http.StatusNotFound, fmt.Sprintf("no BGP protocol %s", name))
+			return
+		}
+	case path == "protocols":
+		body = snapshot.allProtocols
+		if snapshot.allProtocols == nil {
+			body = []ProtocolStatus{}
+		}
+	default:
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if snapshot.updated.IsZero() {
+		apiError(w, http.StatusServiceUnavailable, "no data received from bird yet")
+		return
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("[ERROR] Failed to encode API response: %v", err)
+		apiError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, snapshot.updated.UnixNano()))
+	// ServeContent answers conditional and HEAD requests
+	http.ServeContent(w, r, "", snapshot.updated, bytes.NewReader(append(b, '\n')))
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{message})
+}
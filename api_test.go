@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIHandler(t *testing.T) {
+	updated := time.Now().Add(-time.Minute).Truncate(time.Second)
+	h := newTestHandler(updated, StalePolicyFlag)
+	etag := fmt.Sprintf(`"%x"`, updated.UnixNano())
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name: "status", path: "/api/v1/status", wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var status ShowStatus
+				if err := json.Unmarshal(body, &status); err != nil || status.Hostname != "infra2" || status.RouterId.String() != "192.168.32.79" {
+					t.Errorf("status = %+v, %v", status, err)
+				}
+			},
+		},
+		{
+			name: "peers", path: "/api/v1/peers", wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var peers []ProtocolBGPStatus
+				if err := json.Unmarshal(body, &peers); err != nil || len(peers) != 2 || peers[0].Name != "ber1_gw1" {
+					t.Errorf("peers = %+v, %v", peers, err)
+				}
+			},
+		},
+		{
+			name: "peer", path: "/api/v1/peers/ber1_gw1", wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var peer ProtocolBGPStatus
+				if err := json.Unmarshal(body, &peer); err != nil || peer.NeighborAs != 64846 || peer.Channels["ipv4"].Imported != 21 {
+					t.Errorf("peer = %+v, %v", peer, err)
+				}
+			},
+		},
+		{name: "unknown peer", path: "/api/v1/peers/nope", wantStatus: http.StatusNotFound},
+		{
+			name: "protocols", path: "/api/v1/protocols", wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var protocols []ProtocolStatus
+				if err := json.Unmarshal(body, &protocols); err != nil || len(protocols) != len(h.current().allProtocols) {
+					t.Errorf("protocols = %+v, %v", protocols, err)
+				}
+			},
+		},
+		{name: "unknown path", path: "/api/v1/routes", wantStatus: http.StatusNotFound},
+		{name: "post", method: http.MethodPost, path: "/api/v1/status", wantStatus: http.StatusMethodNotAllowed},
+		{name: "if-none-match", path: "/api/v1/peers", header: map[string]string{"If-None-Match": etag}, wantStatus: http.StatusNotModified},
+		{name: "if-none-match changed", path: "/api/v1/peers", header: map[string]string{"If-None-Match": `"1"`}, wantStatus: http.StatusOK},
+		{name: "if-modified-since", path: "/api/v1/status", header: map[string]string{"If-Modified-Since": updated.UTC().Format(http.TimeFormat)}, wantStatus: http.StatusNotModified},
+		{name: "if-modified-since earlier", path: "/api/v1/status", header: map[string]string{"If-Modified-Since": updated.Add(-time.Second).UTC().Format(http.TimeFormat)}, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tt.path, nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			apiHandler{h}.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusOK {
+				if got := w.Header().Get("ETag"); got != etag {
+					t.Errorf("ETag = %s, want %s", got, etag)
+				}
+				if got := w.Header().Get("Last-Modified"); got != updated.UTC().Format(http.TimeFormat) {
+					t.Errorf("Last-Modified = %s", got)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, w.Body.Bytes())
+			}
+		})
+	}
+
+	t.Run("no data yet", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		apiHandler{&BirdBGPHandler{}}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/peers", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
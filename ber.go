@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// Universal BER tags used by SNMP. The application types and the exception
+// values share their tags with pdu.VariableType, e.g. 0x41 for Counter32
+// and 0x80 for noSuchObject.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+)
+
+// berMaxOIDLength is the maximum number of sub-identifiers of an SNMP OID.
+const berMaxOIDLength = 128
+
+var errBerTruncated = errors.New("truncated BER encoding")
+
+// berTLV encodes a tag, length and value triple with a definite length.
+func berTLV(tag byte, content []byte) []byte {
+	n := len(content)
+	b := make([]byte, 0, n+6)
+	if n < 0x80 {
+		b = append(b, tag, byte(n))
+	} else {
+		size := 0
+		for i := n; i > 0; i >>= 8 {
+			size++
+		}
+		b = append(b, tag, 0x80|byte(size))
+		for i := size - 1; i >= 0; i-- {
+			b = append(b, byte(n>>(8*i)))
+		}
+	}
+	return append(b, content...)
+}
+
+// berInt encodes v as a minimal two's complement integer.
+func berInt(v int64) []byte {
+	n := 1
+	for i := v; i > 127 || i < -128; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// berUint encodes v as a minimal non-negative integer, with a leading zero
+// byte if the high bit is set.
+func berUint(v uint64) []byte {
+	n := 1
+	for i := v; i > 127; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// berOIDContent encodes the sub-identifiers of oid, the first two combined
+// into a single one.
+func berOIDContent(oid value.OID) []byte {
+	if len(oid) < 2 {
+		oid = append(append(value.OID{}, oid...), 0, 0)[:2]
+	}
+	b := berBase128(nil, oid[0]*40+oid[1])
+	for _, id := range oid[2:] {
+		b = berBase128(b, id)
+	}
+	return b
+}
+
+func berBase128(b []byte, v uint32) []byte {
+	n := 1
+	for i := v; i >= 0x80; i >>= 7 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := byte(v>>(7*i)) & 0x7f
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// berValue encodes the content of a value of type t, the go types are the
+// ones go-agentx accepts for it.
+func berValue(t pdu.VariableType, v interface{}) ([]byte, error) {
+	switch t {
+	case pdu.VariableTypeInteger:
+		if v, ok := v.(int32); ok {
+			return berInt(int64(v)), nil
+		}
+	case pdu.VariableTypeOctetString:
+		switch v := v.(type) {
+		case string:
+			return []byte(v), nil
+		case []byte:
+			return v, nil
+		}
+	case pdu.VariableTypeNull, pdu.VariableTypeNoSuchObject, pdu.VariableTypeNoSuchInstance, pdu.VariableTypeEndOfMIBView:
+		return nil, nil
+	case pdu.VariableTypeObjectIdentifier:
+		if v, ok := v.(string); ok {
+			oid, err := value.ParseOID(v)
+			if err != nil {
+				return nil, err
+			}
+			return berOIDContent(oid), nil
+		}
+	case pdu.VariableTypeIPAddress:
+		if v, ok := v.(net.IP); ok {
+			if ip := v.To4(); ip != nil {
+				return ip, nil
+			}
+			return net.IPv4zero.To4(), nil
+		}
+	case pdu.VariableTypeCounter32, pdu.VariableTypeGauge32:
+		if v, ok := v.(uint32); ok {
+			return berUint(uint64(v)), nil
+		}
+	case pdu.VariableTypeTimeTicks:
+		if v, ok := v.(time.Duration); ok {
+			return berUint(uint64(uint32(v / (10 * time.Millisecond)))), nil
+		}
+	case pdu.VariableTypeOpaque:
+		if v, ok := v.([]byte); ok {
+			return v, nil
+		}
+	case pdu.VariableTypeCounter64:
+		if v, ok := v.(uint64); ok {
+			return berUint(v), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported %T value for %s", v, t)
+}
+
+// berVarBind encodes a variable binding.
+func berVarBind(oid value.OID, t pdu.VariableType, v interface{}) ([]byte, error) {
+	content, err := berValue(t, v)
+	if err != nil {
+		return nil, err
+	}
+	b := berTLV(berOID, berOIDContent(oid))
+	return berTLV(berSequence, append(b, berTLV(byte(t), content)...)), nil
+}
+
+// berRead splits the first tag, length and value triple off b.
+func berRead(b []byte) (tag byte, content []byte, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errBerTruncated
+	}
+	tag = b[0]
+	n := int(b[1])
+	b = b[2:]
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 4 {
+			return 0, nil, nil, fmt.Errorf("unsupported BER length of %d bytes", size)
+		}
+		if len(b) < size {
+			return 0, nil, nil, errBerTruncated
+		}
+		n = 0
+		for _, c := range b[:size] {
+			n = n<<8 | int(c)
+		}
+		b = b[size:]
+	}
+	if n < 0 || len(b) < n {
+		return 0, nil, nil, errBerTruncated
+	}
+	return tag, b[:n], b[n:], nil
+}
+
+// berExpect is berRead for a triple with a known tag.
+func berExpect(b []byte, tag byte) (content []byte, rest []byte, err error) {
+	got, content, rest, err := berRead(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if got != tag {
+		return nil, nil, fmt.Errorf("unexpected BER tag 0x%02x, want 0x%02x", got, tag)
+	}
+	return content, rest, nil
+}
+
+// berParseInt decodes a two's complement integer of up to 64 bits.
+func berParseInt(content []byte) (int64, error) {
+	if len(content) == 0 || len(content) > 8 {
+		return 0, fmt.Errorf("invalid BER integer of %d bytes", len(content))
+	}
+	v := int64(int8(content[0]))
+	for _, c := range content[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+// berParseOID decodes the content of an object identifier.
+func berParseOID(content []byte) (value.OID, error) {
+	if len(content) == 0 {
+		return nil, errors.New("empty BER object identifier")
+	}
+	oid := value.OID{}
+	var id uint64
+	for i, c := range content {
+		id = id<<7 | uint64(c&0x7f)
+		if id > 0xffffffff {
+			return nil, errors.New("BER object identifier overflows 32 bits")
+		}
+		if c&0x80 != 0 {
+			if i == len(content)-1 {
+				return nil, errBerTruncated
+			}
+			continue
+		}
+		if len(oid) == 0 {
+			switch {
+			case id < 40:
+				oid = append(oid, 0, uint32(id))
+			case id < 80:
+				oid = append(oid, 1, uint32(id-40))
+			default:
+				oid = append(oid, 2, uint32(id-80))
+			}
+		} else {
+			oid = append(oid, uint32(id))
+		}
+		if len(oid) > berMaxOIDLength {
+			return nil, errors.New("BER object identifier too long")
+		}
+		id = 0
+	}
+	return oid, nil
+}
+
+// berDecoder reads consecutive fields of a constructed value, the first
+// error sticks.
+type berDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *berDecoder) read(tag byte) []byte {
+	if d.err != nil {
+		return nil
+	}
+	var content []byte
+	content, d.b, d.err = berExpect(d.b, tag)
+	return content
+}
+
+func (d *berDecoder) int() int64 {
+	content := d.read(berInteger)
+	if d.err != nil {
+		return 0
+	}
+	v, err := berParseInt(content)
+	if err != nil {
+		d.err = err
+	}
+	return v
+}
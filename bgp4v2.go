@@ -0,0 +1,124 @@
+package main
+
+import (
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// BGP4V2-MIB (draft-ietf-idr-bgp4-mibv2) was never assigned a mib-2 arc,
+// implementations serve it under experimental 1.3.6.1.3.5.1 instead.
+var (
+	oidBgp4V2                         = value.OID{1, 3, 6, 1, 3, 5, 1}
+	oidBgp4V2PeerLocalAddrType        = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 2}
+	oidBgp4V2PeerLocalAddr            = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 3}
+	oidBgp4V2PeerLocalPort            = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 6}
+	oidBgp4V2PeerLocalAs              = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 7}
+	oidBgp4V2PeerLocalIdentifier      = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 8}
+	oidBgp4V2PeerRemotePort           = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 9}
+	oidBgp4V2PeerRemoteAs             = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 10}
+	oidBgp4V2PeerRemoteIdentifier     = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 11}
+	oidBgp4V2PeerAdminStatus          = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 12}
+	oidBgp4V2PeerState                = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 13}
+	oidBgp4V2PeerDescription          = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 2, 1, 14}
+	oidBgp4V2PeerFsmEstablishedTime   = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 4, 1, 1}
+	oidBgp4V2PeerConnectRetryInterval = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 5, 1, 1}
+	oidBgp4V2PeerHoldTimeConfigured   = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 5, 1, 2}
+	oidBgp4V2PeerKeepAliveConfigured  = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 5, 1, 3}
+	oidBgp4V2PeerHoldTime             = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 6, 1, 1}
+	oidBgp4V2PeerKeepAlive            = value.OID{1, 3, 6, 1, 3, 5, 1, 1, 6, 1, 2}
+)
+
+// bgp4V2Instance is the bgp4V2PeerInstance of every peer, bird runs a
+// single BGP instance per daemon.
+const bgp4V2Instance = 1
+
+// bgp4V2Peers returns peers indexed by instance, remote address type and
+// remote address.
+func bgp4V2Peers(protocols []ProtocolBGPStatus) []tableRow[ProtocolBGPStatus] {
+	peers := make([]tableRow[ProtocolBGPStatus], 0, len(protocols))
+	for _, proto := range protocols {
+		addrType, addr := inetAddress(proto.NeighborAddress, proto.NeighborInterface)
+		if addr == nil {
+			continue
+		}
+		index := append(value.OID{bgp4V2Instance}, inetAddressToOid(addrType, addr)...)
+		peers = append(peers, tableRow[ProtocolBGPStatus]{index: index, row: proto})
+	}
+	return peers
+}
+
+// bgp4V2PeerColumns are the served bgp4V2PeerEntry, event times and
+// timers columns in OID order.
+func bgp4V2PeerColumns(status ShowStatus) []tableColumn[ProtocolBGPStatus] {
+	return []tableColumn[ProtocolBGPStatus]{
+		{oidBgp4V2PeerLocalAddrType, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+			addrType, _ := inetAddress(proto.SourceAddress, proto.NeighborInterface)
+			return addrType
+		}},
+		{oidBgp4V2PeerLocalAddr, pdu.VariableTypeOctetString, func(proto ProtocolBGPStatus) interface{} {
+			_, addr := inetAddress(proto.SourceAddress, proto.NeighborInterface)
+			return string(addr)
+		}},
+		{oidBgp4V2PeerLocalPort, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			// bird does not report the local port of the session
+			return uint32(0)
+		}},
+		{oidBgp4V2PeerLocalAs, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return proto.LocalAs
+		}},
+		{oidBgp4V2PeerLocalIdentifier, pdu.VariableTypeOctetString, func(proto ProtocolBGPStatus) interface{} {
+			return string(ipv4OrZero(status.RouterId))
+		}},
+		{oidBgp4V2PeerRemotePort, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return uint32(remotePort(proto))
+		}},
+		{oidBgp4V2PeerRemoteAs, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return proto.NeighborAs
+		}},
+		{oidBgp4V2PeerRemoteIdentifier, pdu.VariableTypeOctetString, func(proto ProtocolBGPStatus) interface{} {
+			return string(ipv4OrZero(proto.NeighborId))
+		}},
+		{oidBgp4V2PeerAdminStatus, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+			// halted(1) for protocols disabled in bird, running(2) otherwise
+			if proto.ProtoState == "down" {
+				return int32(1)
+			}
+			return int32(2)
+		}},
+		{oidBgp4V2PeerState, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+			return bgpState(proto.State)
+		}},
+		{oidBgp4V2PeerDescription, pdu.VariableTypeOctetString, func(proto ProtocolBGPStatus) interface{} {
+			return proto.Name
+		}},
+		{oidBgp4V2PeerFsmEstablishedTime, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			if proto.Up {
+				return uint32(time.Since(proto.Since).Seconds())
+			}
+			return uint32(0)
+		}},
+		{oidBgp4V2PeerConnectRetryInterval, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return uint32(bgpDefaultConnectRetryTime)
+		}},
+		{oidBgp4V2PeerHoldTimeConfigured, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			// bird's default whatever the configuration, it isn't shown
+			return uint32(bgpDefaultHoldTime)
+		}},
+		{oidBgp4V2PeerKeepAliveConfigured, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			// bird's default whatever the configuration, it isn't shown
+			return uint32(bgpDefaultKeepaliveTime)
+		}},
+		{oidBgp4V2PeerHoldTime, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return uint32(proto.HoldTime)
+		}},
+		{oidBgp4V2PeerKeepAlive, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+			return uint32(proto.KeepaliveTime)
+		}},
+	}
+}
+
+func addBgp4V2PeerTable(data *ListHandler, status ShowStatus, protocols []ProtocolBGPStatus) {
+	addTable(data, bgp4V2PeerColumns(status), bgp4V2Peers(protocols))
+}
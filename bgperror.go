@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bgpErrorMessages maps the descriptions bird prints for BGP NOTIFICATION
+// messages (bgp_msg_table in proto/bgp/packets.c) to code and subcode.
+var bgpErrorMessages = map[string][2]byte{
+	"Invalid message header":                  {1, 0},
+	"Connection not synchronized":             {1, 1},
+	"Bad message length":                      {1, 2},
+	"Bad message type":                        {1, 3},
+	"Invalid OPEN message":                    {2, 0},
+	"Unsupported version number":              {2, 1},
+	"Bad peer AS":                             {2, 2},
+	"Bad BGP identifier":                      {2, 3},
+	"Unsupported optional parameter":          {2, 4},
+	"Authentication failure":                  {2, 5},
+	"Unacceptable hold time":                  {2, 6},
+	"Required capability missing":             {2, 7},
+	"No supported AFI/SAFI":                   {2, 8},
+	"Role mismatch":                           {2, 11},
+	"Invalid UPDATE message":                  {3, 0},
+	"Malformed attribute list":                {3, 1},
+	"Unrecognized well-known attribute":       {3, 2},
+	"Missing mandatory attribute":             {3, 3},
+	"Invalid attribute flags":                 {3, 4},
+	"Invalid attribute length":                {3, 5},
+	"Invalid ORIGIN attribute":                {3, 6},
+	"AS routing loop":                         {3, 7},
+	"Invalid NEXT_HOP attribute":              {3, 8},
+	"Optional attribute error":                {3, 9},
+	"Invalid network field":                   {3, 10},
+	"Malformed AS_PATH":                       {3, 11},
+	"Hold timer expired":                      {4, 0},
+	"Finite state machine error":              {5, 0},
+	"Unexpected message in OpenSent state":    {5, 1},
+	"Unexpected message in OpenConfirm state": {5, 2},
+	"Unexpected message in Established state": {5, 3},
+	"Cease":                                {6, 0},
+	"Maximum number of prefixes reached":   {6, 1},
+	"Administrative shutdown":              {6, 2},
+	"Peer de-configured":                   {6, 3},
+	"Administrative reset":                 {6, 4},
+	"Connection rejected":                  {6, 5},
+	"Other configuration change":           {6, 6},
+	"Connection collision resolution":      {6, 7},
+	"Out of resources":                     {6, 8},
+	"Invalid ROUTE-REFRESH message":        {7, 0},
+	"Invalid ROUTE-REFRESH message length": {7, 1},
+}
+
+// bgpLastErrorCode returns the BGP NOTIFICATION code and subcode of a bird
+// "Last error" value, zeros if the last error was not a BGP notification.
+func bgpLastErrorCode(lastError string) (byte, byte) {
+	msg, ok := strings.CutPrefix(lastError, "Received: ")
+	if !ok {
+		msg, ok = strings.CutPrefix(lastError, "BGP Error: ")
+	}
+	if !ok {
+		return 0, 0
+	}
+	// shutdown communication (RFC 8203) is appended after the description
+	msg, _, _ = strings.Cut(msg, ": ")
+	if code, ok := bgpErrorMessages[msg]; ok {
+		return code[0], code[1]
+	}
+	var code, subcode byte
+	if _, err := fmt.Sscanf(msg, "Unknown error %d.%d", &code, &subcode); err == nil {
+		return code, subcode
+	}
+	return 0, 0
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func Test_bgpLastErrorCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastError   string
+		wantCode    byte
+		wantSubcode byte
+	}{
+		{name: "empty", lastError: "", wantCode: 0, wantSubcode: 0},
+		{name: "socket", lastError: "Socket: No route to host", wantCode: 0, wantSubcode: 0},
+		{name: "received", lastError: "Received: Hold timer expired", wantCode: 4, wantSubcode: 0},
+		{name: "sent", lastError: "BGP Error: Bad peer AS", wantCode: 2, wantSubcode: 2},
+		{name: "shutdown communication", lastError: `Received: Administrative shutdown: "maintenance"`, wantCode: 6, wantSubcode: 2},
+		{name: "unknown", lastError: "Received: Unknown error 6.42", wantCode: 6, wantSubcode: 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, subcode := bgpLastErrorCode(tt.lastError)
+			if code != tt.wantCode || subcode != tt.wantSubcode {
+				t.Errorf("bgpLastErrorCode() = %v, %v, want %v, %v", code, subcode, tt.wantCode, tt.wantSubcode)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+var (
+	oidBgpEstablishedNotification   = value.OID{1, 3, 6, 1, 2, 1, 15, 0, 1}
+	oidBgpBackwardTransNotification = value.OID{1, 3, 6, 1, 2, 1, 15, 0, 2}
+)
+
+// bgpPeerTransition is a notification due for a peer between two snapshots.
+type bgpPeerTransition struct {
+	trapOID value.OID
+	proto   ProtocolBGPStatus
+}
+
+// bgpPeerTransitions compares two consecutive snapshots of bgp protocols and
+// returns the notifications to send. A peer that is established in both
+// snapshots but was established again in between has flapped, it gets a
+// backward transition followed by an established notification.
+func bgpPeerTransitions(prev []ProtocolBGPStatus, cur []ProtocolBGPStatus) []bgpPeerTransition {
+	prevByName := make(map[string]ProtocolBGPStatus, len(prev))
+	for _, proto := range prev {
+		prevByName[proto.Name] = proto
+	}
+
+	transitions := []bgpPeerTransition{}
+	for _, proto := range cur {
+		old, ok := prevByName[proto.Name]
+		if !ok {
+			continue
+		}
+		oldState, newState := bgpState(old.State), bgpState(proto.State)
+		switch {
+		case oldState == newState && newState == bgpStateToInt["Established"] && !old.Since.Equal(proto.Since):
+			transitions = append(transitions,
+				bgpPeerTransition{trapOID: oidBgpBackwardTransNotification, proto: proto},
+				bgpPeerTransition{trapOID: oidBgpEstablishedNotification, proto: proto},
+			)
+		case newState < oldState:
+			transitions = append(transitions, bgpPeerTransition{trapOID: oidBgpBackwardTransNotification, proto: proto})
+		case newState > oldState && newState == bgpStateToInt["Established"]:
+			transitions = append(transitions, bgpPeerTransition{trapOID: oidBgpEstablishedNotification, proto: proto})
+		}
+	}
+	return transitions
+}
+
+// variables returns the varbinds of the notification, BGP4-MIB defines
+// them for bgpPeerTable only so IPv6 peers yield none.
+func (t bgpPeerTransition) variables() (pdu.Variables, bool) {
+	if t.proto.NeighborAddress.To4() == nil {
+		return nil, false
+	}
+	index := ipToOid(t.proto.NeighborAddress)
+	code, subcode := bgpLastErrorCode(t.proto.LastError)
+
+	variables := pdu.Variables{}
+	variables.Add(append(oidBgpPeerRemoteAddr, index...), pdu.VariableTypeIPAddress, t.proto.NeighborAddress.To4())
+	variables.Add(append(oidBgpPeerLastError, index...), pdu.VariableTypeOctetString, string([]byte{code, subcode}))
+	variables.Add(append(oidBgpPeerState, index...), pdu.VariableTypeInteger, bgpState(t.proto.State))
+	return variables, true
+}
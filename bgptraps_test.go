@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_bgpPeerTransitions(t *testing.T) {
+	since := time.Date(2024, 10, 12, 20, 41, 14, 0, time.Local)
+	peer := func(state string, since time.Time) []ProtocolBGPStatus {
+		return []ProtocolBGPStatus{{Name: "gw1", State: state, Since: since}}
+	}
+	tests := []struct {
+		name string
+		prev []ProtocolBGPStatus
+		cur  []ProtocolBGPStatus
+		want []value.OID
+	}{
+		{name: "first snapshot", prev: nil, cur: peer("Established", since), want: []value.OID{}},
+		{name: "unchanged", prev: peer("Established", since), cur: peer("Established", since), want: []value.OID{}},
+		{name: "established", prev: peer("OpenConfirm", since), cur: peer("Established", since), want: []value.OID{oidBgpEstablishedNotification}},
+		{name: "went down", prev: peer("Established", since), cur: peer("Active", since), want: []value.OID{oidBgpBackwardTransNotification}},
+		{name: "progressing", prev: peer("Idle", since), cur: peer("Connect", since), want: []value.OID{}},
+		{name: "closing", prev: peer("Established", since), cur: peer("Close", since), want: []value.OID{oidBgpBackwardTransNotification}},
+		{name: "unknown state", prev: peer("Connect", since), cur: peer("Whatever", since), want: []value.OID{oidBgpBackwardTransNotification}},
+		{name: "flapped", prev: peer("Established", since), cur: peer("Established", since.Add(time.Second)), want: []value.OID{oidBgpBackwardTransNotification, oidBgpEstablishedNotification}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []value.OID{}
+			for _, transition := range bgpPeerTransitions(tt.prev, tt.cur) {
+				got = append(got, transition.trapOID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bgpPeerTransitions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
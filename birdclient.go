@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Classes of final reply codes of the bird cli protocol, the first of
+// their four digits. Codes 1xxx and 2xxx are data lines and table headings.
+const (
+	birdClassOK           = 0 // successful command
+	birdClassRuntimeError = 8 // failed command, e.g. an unknown protocol
+	birdClassSyntaxError  = 9 // unparsable command
+)
+
+// birdCodeReady is the code of the banner bird greets clients with.
+const birdCodeReady = 1
+
+// BirdReplyError is an error reply of bird: 8xxx for runtime errors such as
+// an unknown protocol and 9xxx for syntax errors. The connection stays
+// usable after it.
+type BirdReplyError struct {
+	Code    int
+	Message string
+}
+
+func (e *BirdReplyError) Error() string {
+	return fmt.Sprintf("%04d %s", e.Code, e.Message)
+}
+
+// birdReplyLine is a line of a bird reply. Lines continuing the previous
+// code have the code of that line.
+type birdReplyLine struct {
+	code  int
+	final bool // "DDDD text" rather than "DDDD-text" or " text"
+	text  string
+}
+
+// parseBirdReplyLine parses "DDDD-text", "DDDD text" and " text" lines,
+// prev is the code of the preceding line.
+func parseBirdReplyLine(line string, prev int) (birdReplyLine, error) {
+	if strings.HasPrefix(line, " ") {
+		return birdReplyLine{code: prev, text: line[1:]}, nil
+	}
+	if len(line) < 5 || (line[4] != ' ' && line[4] != '-') {
+		return birdReplyLine{}, fmt.Errorf("malformed reply line %q", line)
+	}
+	code, err := strconv.Atoi(line[:4])
+	if err != nil {
+		return birdReplyLine{}, fmt.Errorf("malformed reply code in %q", line)
+	}
+	return birdReplyLine{code: code, final: line[4] == ' ', text: line[5:]}, nil
+}
+
+// terminates tells whether l is the last line of a reply.
+func (l birdReplyLine) terminates() bool {
+	if !l.final {
+		return false
+	}
+	switch l.code / 1000 {
+	case birdClassOK, birdClassRuntimeError, birdClassSyntaxError:
+		return true
+	}
+	return false
+}
+
+// BirdClient speaks the bird cli protocol on a control socket. It is not
+// safe for concurrent use, and a command failing with anything else than a
+// *BirdReplyError leaves the connection in an unknown state, so it must be
+// closed.
+type BirdClient struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	transcript io.Writer // raw exchange, commands prefixed with "> "
+
+	// Timeout bounds every command in addition to the context deadline,
+	// zero means no timeout.
+	Timeout time.Duration
+}
+
+// DialBird connects to the bird control socket at path and reads the
+// "0001 BIRD x.y.z ready." banner.
+func DialBird(ctx context.Context, path string) (*BirdClient, error) {
+	return DialBirdRecording(ctx, path, nil)
+}
+
+// DialBirdRecording is DialBird writing the banner, the commands and the
+// raw replies to transcript, in the format read by LoadBirdRecording.
+func DialBirdRecording(ctx context.Context, path string, transcript io.Writer) (*BirdClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, newBirdError("connect", err)
+	}
+	c := &BirdClient{conn: conn, r: bufio.NewReader(conn), transcript: transcript}
+
+	stop := c.deadline(ctx)
+	defer stop()
+	line, err := c.readLine(0)
+	if err != nil {
+		conn.Close()
+		return nil, newBirdError("connect", err)
+	}
+	if line.code != birdCodeReady {
+		conn.Close()
+		return nil, newBirdError("connect", fmt.Errorf("unexpected banner %04d %s", line.code, line.text))
+	}
+	return c, nil
+}
+
+// deadline applies the context deadline and the client timeout to the
+// connection and aborts pending I/O once ctx is cancelled.
+func (c *BirdClient) deadline(ctx context.Context) (stop func()) {
+	deadline, ok := ctx.Deadline()
+	if c.Timeout > 0 {
+		if timeout := time.Now().Add(c.Timeout); !ok || timeout.Before(deadline) {
+			deadline, ok = timeout, true
+		}
+	}
+	if ok {
+		c.conn.SetDeadline(deadline)
+	}
+	stopAfter := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() {
+		stopAfter()
+		c.conn.SetDeadline(time.Time{})
+	}
+}
+
+func (c *BirdClient) readLine(prev int) (birdReplyLine, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return birdReplyLine{}, err
+	}
+	if c.transcript != nil {
+		io.WriteString(c.transcript, line)
+	}
+	return parseBirdReplyLine(strings.TrimSuffix(line, "\n"), prev)
+}
+
+// Command runs a cli command and returns its output the way birdc prints
+// it, one line per reply line without the reply codes. Error replies are
+// returned as *BirdReplyError wrapped in a *BirdError.
+func (c *BirdClient) Command(ctx context.Context, command string) (string, error) {
+	stop := c.deadline(ctx)
+	defer stop()
+
+	command = strings.TrimRight(command, "\n")
+	if _, err := c.conn.Write([]byte(command + "\n")); err != nil {
+		return "", newBirdError(command, contextError(ctx, err))
+	}
+	if c.transcript != nil {
+		io.WriteString(c.transcript, birdRecordingCommand+command+"\n")
+	}
+
+	var out strings.Builder
+	code := 0
+	for {
+		line, err := c.readLine(code)
+		if err != nil {
+			return "", newBirdError(command, contextError(ctx, err))
+		}
+		code = line.code
+		if line.terminates() {
+			if code/1000 != birdClassOK {
+				return "", newBirdError(command, &BirdReplyError{Code: code, Message: line.text})
+			}
+			// the final line of a successful command may carry text,
+			// e.g. "0013 Daemon is up and running"
+			if line.text != "" {
+				out.WriteString(line.text + "\n")
+			}
+			return out.String(), nil
+		}
+		out.WriteString(line.text + "\n")
+	}
+}
+
+// Echo subscribes to bird's log messages of the classes in mask, e.g.
+// "all" or "{ info, trace }", they are read with Messages. The connection
+// can't be used for other commands afterwards.
+func (c *BirdClient) Echo(ctx context.Context, mask string) error {
+	_, err := c.Command(ctx, "echo "+mask)
+	return err
+}
+
+// Messages calls fn for every log message after Echo until ctx is cancelled
+// or the connection fails.
+func (c *BirdClient) Messages(ctx context.Context, fn func(msg string)) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return newBirdError("echo", contextError(ctx, err))
+		}
+		// log messages are prefixed with "+", a ring buffer overflow is
+		// reported as a bare "<N messages lost>" line
+		line = strings.TrimSuffix(line, "\n")
+		fn(strings.TrimPrefix(line, "+"))
+	}
+}
+
+// contextError prefers the context error over the i/o error it caused.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// Close closes the connection.
+func (c *BirdClient) Close() error {
+	return c.conn.Close()
+}
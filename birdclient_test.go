@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBirdReplies are the raw replies of the fake bird by command, unknown
+// commands get a syntax error and "hang" never gets a reply.
+var fakeBirdReplies = map[string]string{
+	"show status": "1000-BIRD 2.15.1\n0013 Daemon is up and running\n",
+	"show protocols": "2002-Name       Proto      Table      State  Since         Info\n" +
+		"1002-device1    Device     ---        up     2024-10-12 20:41:10  \n" +
+		" direct1    Direct     ---        up     2024-10-12 20:41:10  \n" +
+		"0000 \n",
+	"show protocols all nonexistent": "8003 nonexistent: No such protocol\n",
+}
+
+// birdReply encodes birdc output as a raw reply of data lines.
+func birdReply(out string) string {
+	var reply strings.Builder
+	for _, line := range strings.Split(strings.Trim(out, "\n"), "\n") {
+		reply.WriteString("1006-" + line + "\n")
+	}
+	reply.WriteString("0000 \n")
+	return reply.String()
+}
+
+// fakeBird serves the bird banner and answers commands from replies until
+// closed.
+type fakeBird struct {
+	listener net.Listener
+	conns    chan net.Conn
+}
+
+func startFakeBird(t *testing.T, path string, replies map[string]string) *fakeBird {
+	t.Helper()
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeBird{listener: listener, conns: make(chan net.Conn, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.conns <- conn
+			go func() {
+				conn.Write([]byte("0001 BIRD 2.15.1 ready.\n"))
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if scanner.Text() == "hang" {
+						continue
+					}
+					reply, ok := replies[scanner.Text()]
+					if !ok {
+						reply = "9001 syntax error, unexpected CF_SYM_UNDEFINED\n"
+					}
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return f
+}
+
+func (f *fakeBird) Close() {
+	f.listener.Close()
+	for {
+		select {
+		case conn := <-f.conns:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func Test_parseBirdReplyLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		prev    int
+		want    birdReplyLine
+		wantErr bool
+	}{
+		{name: "banner", line: "0001 BIRD 2.15.1 ready.", want: birdReplyLine{code: 1, final: true, text: "BIRD 2.15.1 ready."}},
+		{name: "data", line: "1002-device1    Device", want: birdReplyLine{code: 1002, text: "device1    Device"}},
+		{name: "continuation", line: "   BGP state:          Established", prev: 1006, want: birdReplyLine{code: 1006, text: "  BGP state:          Established"}},
+		{name: "ok", line: "0000 ", want: birdReplyLine{code: 0, final: true}},
+		{name: "runtime error", line: "8003 nonexistent: No such protocol", want: birdReplyLine{code: 8003, final: true, text: "nonexistent: No such protocol"}},
+		{name: "no separator", line: "0000", wantErr: true},
+		{name: "no code", line: "BIRD 2.15.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBirdReplyLine(tt.line, tt.prev)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBirdReplyLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBirdReplyLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBirdClientCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+
+	c, err := DialBird(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name     string
+		command  string
+		want     string
+		wantCode int
+	}{
+		{name: "final line text", command: "show status", want: "BIRD 2.15.1\nDaemon is up and running\n"},
+		{
+			name:    "continuation lines",
+			command: "show protocols",
+			want: "Name       Proto      Table      State  Since         Info\n" +
+				"device1    Device     ---        up     2024-10-12 20:41:10  \n" +
+				"direct1    Direct     ---        up     2024-10-12 20:41:10  \n",
+		},
+		{name: "runtime error", command: "show protocols all nonexistent", wantCode: 8003},
+		{name: "syntax error", command: "shwo status", wantCode: 9001},
+		{name: "usable after errors", command: "show status", want: "BIRD 2.15.1\nDaemon is up and running\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Command(context.Background(), tt.command)
+			var replyErr *BirdReplyError
+			if tt.wantCode != 0 {
+				var birdErr *BirdError
+				if !errors.As(err, &birdErr) || !errors.As(err, &replyErr) || replyErr.Code != tt.wantCode {
+					t.Fatalf("Command() error = %v, want reply code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Command() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBirdClientTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+
+	c, err := DialBird(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Timeout = 50 * time.Millisecond
+	if _, err := c.Command(context.Background(), "hang"); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Command() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestBirdClientCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+
+	c, err := DialBird(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.Command(ctx, "hang"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Command() error = %v, want context canceled", err)
+	}
+}
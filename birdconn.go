@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Backoff between reconnect attempts, doubled after every failed attempt.
+const (
+	birdReconnectMinBackoff = 500 * time.Millisecond
+	birdReconnectMaxBackoff = 30 * time.Second
+	birdConnectTimeout      = 5 * time.Second
+)
+
+var errBirdNotConnected = errors.New("not connected")
+
+// BirdConnState is the state of the bird control socket connection, the
+// values match birdConnectionState in BIRD-MIB.
+type BirdConnState int32
+
+const (
+	BirdConnected  BirdConnState = 1
+	BirdConnecting BirdConnState = 2
+)
+
+func (s BirdConnState) String() string {
+	switch s {
+	case BirdConnected:
+		return "connected"
+	case BirdConnecting:
+		return "connecting"
+	}
+	return fmt.Sprintf("BirdConnState(%d)", int32(s))
+}
+
+// BirdConnStatus is a snapshot of the connection state.
+type BirdConnStatus struct {
+	State      BirdConnState
+	Since      time.Time // time of the last state change
+	Reconnects uint32    // successful connects after the first one
+	LastError  string    // last command or connect error, empty if none
+}
+
+// BirdConn is a supervised connection to the bird control socket. Commands
+// fail fast while bird is unreachable and a background goroutine redials
+// with exponential backoff, so the agent heals itself after a bird restart.
+type BirdConn struct {
+	path       string
+	timeout    time.Duration
+	transcript io.Writer
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu     sync.Mutex // serializes commands
+	client *BirdClient
+
+	statusMu  sync.Mutex
+	status    BirdConnStatus
+	connected bool // connected at least once
+
+	broken chan struct{}
+	done   chan struct{}
+}
+
+// NewBirdConn connects to the bird control socket at path, every command
+// is limited to timeout. It doesn't fail if bird is unreachable, the
+// connection is retried in the background.
+func NewBirdConn(path string, timeout time.Duration) *BirdConn {
+	return newBirdConn(path, timeout, nil, birdReconnectMinBackoff, birdReconnectMaxBackoff)
+}
+
+// NewBirdConnRecording is NewBirdConn writing the exchange of every
+// connection to transcript, see DialBirdRecording.
+func NewBirdConnRecording(path string, timeout time.Duration, transcript io.Writer) *BirdConn {
+	return newBirdConn(path, timeout, transcript, birdReconnectMinBackoff, birdReconnectMaxBackoff)
+}
+
+func newBirdConn(path string, timeout time.Duration, transcript io.Writer, minBackoff time.Duration, maxBackoff time.Duration) *BirdConn {
+	c := &BirdConn{
+		path:       path,
+		timeout:    timeout,
+		transcript: transcript,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		status:     BirdConnStatus{State: BirdConnecting, Since: time.Now()},
+		broken:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		log.Printf("[ERROR] Failed to connect bird at %s: %v", path, err)
+		c.broken <- struct{}{}
+	}
+	go c.supervise()
+	return c
+}
+
+// supervise redials the socket every time a command breaks the connection.
+func (c *BirdConn) supervise() {
+	for {
+		select {
+		case <-c.broken:
+		case <-c.done:
+			return
+		}
+		backoff := c.minBackoff
+		for {
+			err := c.connect()
+			if err == nil {
+				break
+			}
+			log.Printf("[WARN] Failed to connect bird at %s, retrying in %s: %v", c.path, backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-c.done:
+				return
+			}
+			backoff = min(backoff*2, c.maxBackoff)
+		}
+	}
+}
+
+// connect dials the socket and reads the "0001 BIRD x.y.z ready." banner.
+func (c *BirdConn) connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), birdConnectTimeout)
+	defer cancel()
+	client, err := DialBirdRecording(ctx, c.path, c.transcript)
+	if err != nil {
+		c.setState(BirdConnecting, err)
+		return err
+	}
+	client.Timeout = c.timeout
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	c.setState(BirdConnected, nil)
+	log.Printf("[INFO] Connected to bird at %s", c.path)
+	return nil
+}
+
+func (c *BirdConn) setState(state BirdConnState, err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	if c.status.State == state {
+		return
+	}
+	if state == BirdConnected {
+		if c.connected {
+			c.status.Reconnects++
+		}
+		c.connected = true
+	}
+	c.status.State = state
+	c.status.Since = time.Now()
+}
+
+// Status returns the current connection state.
+func (c *BirdConn) Status() BirdConnStatus {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.status
+}
+
+// Command runs a cli command and returns its output. Error replies of bird
+// are returned as they are, any other failure drops the connection and
+// hands it over to the supervisor for reconnecting.
+func (c *BirdConn) Command(ctx context.Context, command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return "", newBirdError(command, errBirdNotConnected)
+	}
+	out, err := c.client.Command(ctx, command)
+	var replyErr *BirdReplyError
+	if err != nil && !errors.As(err, &replyErr) {
+		log.Printf("[WARN] Lost bird connection at %s: %v", c.path, err)
+		c.client.Close()
+		c.client = nil
+		c.setState(BirdConnecting, err)
+		select {
+		case c.broken <- struct{}{}:
+		default:
+		}
+	}
+	return out, err
+}
+
+// Close stops reconnecting and closes the connection.
+func (c *BirdConn) Close() error {
+	close(c.done)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return nil
+	}
+	err := c.client.Close()
+	c.client = nil
+	return err
+}
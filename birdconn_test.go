@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitBirdConnState(t *testing.T, c *BirdConn, state BirdConnState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Status().State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection state is %s, want %s", c.Status().State, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBirdConnReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+
+	c := newBirdConn(path, time.Second, nil, 10*time.Millisecond, 50*time.Millisecond)
+	defer c.Close()
+	if got := c.Status().State; got != BirdConnected {
+		t.Fatalf("state = %s, want connected", got)
+	}
+	want := "BIRD 2.15.1\nDaemon is up and running\n"
+	if got, err := c.Command(context.Background(), "show status"); err != nil || got != want {
+		t.Fatalf("Command() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := c.Command(context.Background(), "shwo status"); err == nil {
+		t.Fatal("Command() succeeded with a syntax error")
+	}
+	if got := c.Status().State; got != BirdConnected {
+		t.Fatalf("state after an error reply = %s, want connected", got)
+	}
+
+	bird.Close()
+	if _, err := c.Command(context.Background(), "show status"); err == nil {
+		t.Fatal("Command() succeeded on a closed socket")
+	}
+	if got := c.Status().State; got != BirdConnecting {
+		t.Fatalf("state = %s, want connecting", got)
+	}
+	if _, err := c.Command(context.Background(), "show status"); err == nil {
+		t.Fatal("Command() succeeded while disconnected")
+	}
+
+	bird = startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+	waitBirdConnState(t, c, BirdConnected)
+	if got, err := c.Command(context.Background(), "show status"); err != nil || got != want {
+		t.Fatalf("Command() = %q, %v, want %q", got, err, want)
+	}
+	if got := c.Status().Reconnects; got != 1 {
+		t.Errorf("reconnects = %d, want 1", got)
+	}
+}
+
+func TestBirdConnUnreachable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	c := newBirdConn(path, time.Second, nil, 10*time.Millisecond, 50*time.Millisecond)
+	defer c.Close()
+	if got := c.Status(); got.State != BirdConnecting || got.LastError == "" {
+		t.Fatalf("status = %+v, want connecting with an error", got)
+	}
+
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+	waitBirdConnState(t, c, BirdConnected)
+	if got := c.Status().Reconnects; got != 0 {
+		t.Errorf("reconnects = %d, want 0", got)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// birdEchoMask are the log classes followed: protocol state changes are
+// traces, BGP session errors remote and reconfigurations info messages.
+const birdEchoMask = "{ info, remote, trace }"
+
+// birdLogStateChange starts the state change messages of protocols with
+// the states debug flag.
+const birdLogStateChange = "State changed to "
+
+// birdLogEvents are the messages about a protocol that report a change of
+// its state, other messages such as packet and route traces are ignored.
+var birdLogEvents = []string{birdLogStateChange, "Received: ", "Error: "}
+
+// BirdLogWatcher follows bird's log over a second control connection with
+// "echo" and reports the protocols changing state, so their changes can be
+// picked up right away instead of on the next poll.
+//
+// Protocol state changes are logged only for protocols with the states
+// debug flag, e.g. "debug protocols { states };" in bird.conf. Until one is
+// seen the log may miss them, which StateChanges tells.
+type BirdLogWatcher struct {
+	path         string
+	connected    atomic.Bool
+	stateChanges atomic.Bool
+
+	// Events receives the name of every protocol a log message is about,
+	// or an empty name when any protocol may have changed: after
+	// connecting, on lost messages and on reconfiguration.
+	Events chan string
+
+	// Transcript receives the exchange of every connection if set, see
+	// DialBirdRecording.
+	Transcript io.Writer
+}
+
+func NewBirdLogWatcher(path string) *BirdLogWatcher {
+	return &BirdLogWatcher{path: path, Events: make(chan string, 64)}
+}
+
+// StateChanges tells whether log messages are being received and state
+// changes were seen among them, so the log can be relied on to report them.
+func (w *BirdLogWatcher) StateChanges() bool {
+	return w.connected.Load() && w.stateChanges.Load()
+}
+
+// Run follows the log until ctx is cancelled, reconnecting with
+// exponential backoff.
+func (w *BirdLogWatcher) Run(ctx context.Context) {
+	backoff := birdReconnectMinBackoff
+	for ctx.Err() == nil {
+		err := w.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		w.stateChanges.Store(false)
+		if w.connected.Swap(false) {
+			backoff = birdReconnectMinBackoff
+		}
+		log.Printf("[WARN] Failed to follow bird log at %s, retrying in %s: %v", w.path, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, birdReconnectMaxBackoff)
+	}
+}
+
+func (w *BirdLogWatcher) follow(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, birdConnectTimeout)
+	client, err := DialBirdRecording(dialCtx, w.path, w.Transcript)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	client.Timeout = birdConnectTimeout
+	if err := client.Echo(ctx, birdEchoMask); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Following bird log at %s", w.path)
+	w.connected.Store(true)
+	// anything may have changed while not following the log
+	w.send(ctx, "")
+	return client.Messages(ctx, func(msg string) {
+		name, ok := birdLogProtocol(msg)
+		if !ok {
+			return
+		}
+		if name != "" && !w.stateChanges.Load() && strings.Contains(msg, ": "+birdLogStateChange) {
+			log.Printf("[INFO] Bird logs protocol state changes, polling every resync interval only")
+			w.stateChanges.Store(true)
+		}
+		w.send(ctx, name)
+	})
+}
+
+func (w *BirdLogWatcher) send(ctx context.Context, name string) {
+	select {
+	case w.Events <- name:
+	case <-ctx.Done():
+	}
+}
+
+// birdLogProtocol returns the protocol a state change message is about,
+// e.g. "bgp1" for "bgp1: State changed to up" or "bgp1.ipv4: State changed
+// to up", or an empty name for messages that may concern any protocol.
+// Other messages are not reported.
+func birdLogProtocol(msg string) (string, bool) {
+	if strings.HasSuffix(msg, " messages lost>") || strings.HasPrefix(msg, "Reconfigur") {
+		return "", true
+	}
+	name, event, ok := strings.Cut(msg, ": ")
+	if !ok {
+		return "", false
+	}
+	// channel messages are prefixed with protocol.channel
+	name, _, _ = strings.Cut(name, ".")
+	if !isBirdSymbol(name) {
+		return "", false
+	}
+	for _, prefix := range birdLogEvents {
+		if strings.HasPrefix(event, prefix) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// isBirdSymbol tells whether s is a valid bird symbol name, which makes it
+// safe to be used in a command.
+func isBirdSymbol(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
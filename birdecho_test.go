@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_birdLogProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		wantName string
+		wantOk   bool
+	}{
+		{name: "state change", msg: "ber1_gw1: State changed to up", wantName: "ber1_gw1", wantOk: true},
+		{name: "bgp error", msg: "xxx_gw1: Received: Hold timer expired", wantName: "xxx_gw1", wantOk: true},
+		{name: "lost messages", msg: "<12 messages lost>", wantOk: true},
+		{name: "reconfiguration", msg: "Reconfigured", wantOk: true},
+		{name: "channel state change", msg: "ber1_gw1.ipv4: State changed to up", wantName: "ber1_gw1", wantOk: true},
+		{name: "route trace", msg: "ber1_gw1 > added [best] 10.0.0.0/8 unicast", wantOk: false},
+		{name: "packet trace", msg: "ber1_gw1: Got UPDATE", wantOk: false},
+		{name: "bgp trace", msg: "ber1_gw1: Sending KEEPALIVE", wantOk: false},
+		{name: "not a symbol", msg: "Kernel dump failed: Permission denied", wantOk: false},
+		{name: "command injection", msg: "x; down: y", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotOk := birdLogProtocol(tt.msg)
+			if gotName != tt.wantName || gotOk != tt.wantOk {
+				t.Errorf("birdLogProtocol() = %q, %v, want %q, %v", gotName, gotOk, tt.wantName, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestBirdLogWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, map[string]string{
+		"echo " + birdEchoMask: "0000 \n+ber1_gw1: Got UPDATE\n+xxx_gw1: State changed to up\n",
+	})
+	defer bird.Close()
+
+	w := NewBirdLogWatcher(path)
+	if w.StateChanges() {
+		t.Error("StateChanges() before following the log")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// everything after connecting, then the protocol changing state only
+	for _, want := range []string{"", "xxx_gw1"} {
+		select {
+		case got := <-w.Events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event %q", want)
+		}
+	}
+	if !w.StateChanges() {
+		t.Error("StateChanges() = false after a state change message")
+	}
+}
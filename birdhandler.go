@@ -1,133 +1,574 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"log"
+	"reflect"
+	"sort"
+	"sync/atomic"
 	"time"
 
-	"github.com/natesales/go-bird"
 	"github.com/posteo/go-agentx"
 	"github.com/posteo/go-agentx/pdu"
 	"github.com/posteo/go-agentx/value"
 )
 
 var (
-	oidBgp                       = value.OID{1, 3, 6, 1, 2, 1, 15}
-	oidBgpVersion                = value.OID{1, 3, 6, 1, 2, 1, 15, 1}
-	oidBgpLocalAs                = value.OID{1, 3, 6, 1, 2, 1, 15, 2}
-	oidBgpPeerState              = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 2}
-	oidBgpPeerRemoteAddr         = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 7}
-	oidBgpPeerFsmEstablishedTime = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 16}
-	oidBgpIdentifier             = value.OID{1, 3, 6, 1, 2, 1, 15, 4}
+	oidBgp                         = value.OID{1, 3, 6, 1, 2, 1, 15}
+	oidBgpVersion                  = value.OID{1, 3, 6, 1, 2, 1, 15, 1}
+	oidBgpLocalAs                  = value.OID{1, 3, 6, 1, 2, 1, 15, 2}
+	oidBgpPeerIdentifier           = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 1}
+	oidBgpPeerState                = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 2}
+	oidBgpPeerAdminStatus          = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 3}
+	oidBgpPeerNegotiatedVersion    = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 4}
+	oidBgpPeerLocalAddr            = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 5}
+	oidBgpPeerLocalPort            = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 6}
+	oidBgpPeerRemoteAddr           = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 7}
+	oidBgpPeerRemotePort           = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 8}
+	oidBgpPeerRemoteAs             = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 9}
+	oidBgpPeerLastError            = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 14}
+	oidBgpPeerFsmEstablishedTime   = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 16}
+	oidBgpPeerConnectRetryInterval = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 17}
+	oidBgpPeerHoldTime             = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 18}
+	oidBgpPeerKeepAlive            = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 19}
+	oidBgpPeerHoldTimeConfigured   = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 20}
+	oidBgpPeerKeepAliveConfigured  = value.OID{1, 3, 6, 1, 2, 1, 15, 3, 1, 21}
+	oidBgpIdentifier               = value.OID{1, 3, 6, 1, 2, 1, 15, 4}
 )
 
+// birdSnapshot is a generation of bird data and the tree built from it. It
+// is never modified once published.
+type birdSnapshot struct {
+	birdT        time.Time // time of the last successful refresh
+	updated      time.Time // time of the last refresh changing the data
+	status       ShowStatus
+	allProtocols []ProtocolStatus
+	protocols    []ProtocolBGPStatus
+	data         *ListHandler
+}
+
 // 1.3.6.1.2.1.15
+//
+// Refreshes run on a single goroutine and publish a new snapshot with an
+// atomic pointer swap, so SNMP requests never wait for a refresh.
 type BirdBGPHandler struct {
-	bird  *bird.Daemon
-	birdT time.Time
-	mu    *sync.RWMutex
-	data  *ListHandler
+	conn     *BirdConn
+	started  time.Time
+	snapshot atomic.Pointer[birdSnapshot]
+
+	jnxPeerIndexes peerIndexes // owned by the refreshing goroutine
+
+	// Notifier receives bgpEstablishedNotification and
+	// bgpBackwardTransNotification on peer state changes, if set.
+	Notifier *AgentxNotifier
+
+	// MaxDataAge is the age after which data is considered stale, zero
+	// disables staleness detection. StalePolicy decides how stale data is
+	// served.
+	MaxDataAge  time.Duration
+	StalePolicy string
 }
 
-func NewBirdBGPHandler(birdSocketPath string) (*BirdBGPHandler, error) {
-	d, err := bird.New(birdSocketPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect bird: %w", err)
+// Serving policies for stale data. The agent scalars are always served.
+const (
+	StalePolicyFlag           = "flag"           // serve it, birdDataStale is true(1)
+	StalePolicyNoSuchInstance = "nosuchinstance" // hide it
+	StalePolicyGenErr         = "generr"         // fail requests for it
+)
+
+var errStaleData = errors.New("bird data is stale")
+
+// NewBirdBGPHandler returns a handler refreshing its data through conn. An
+// unreachable bird is not fatal, the handler serves an empty tree until the
+// connection is established.
+func NewBirdBGPHandler(ctx context.Context, conn *BirdConn) *BirdBGPHandler {
+	handler := &BirdBGPHandler{
+		conn:        conn,
+		started:     time.Now(),
+		StalePolicy: StalePolicyFlag,
 	}
-	d.Read(nil)
-	handler := &BirdBGPHandler{bird: d, mu: &sync.RWMutex{}}
-	if err := handler.Refresh(); err != nil {
-		return nil, fmt.Errorf("failed to refresh bird stats: %w", err)
+	if err := handler.Refresh(ctx); err != nil {
+		log.Printf("[ERROR] Failed to refresh BGP data: %v", err)
 	}
-	return handler, nil
+	return handler
 }
 
 var bgpStateToInt = map[string]int32{
 	"Established": 6,
+	"OpenConfirm": 5,
+	"OpenSent":    4,
 	"Active":      3,
 	"Connect":     2,
 	"Idle":        1,
 	"Down":        1,
 	"Passive":     1,
+	"Close":       1,
+}
+
+// bgpState returns the BGP4-MIB bgpPeerState of a bird BGP state, idle(1)
+// for unknown states.
+func bgpState(state string) int32 {
+	if n, ok := bgpStateToInt[state]; ok {
+		return n
+	}
+	return 1
+}
+
+// bgpPort is the remote port of a session unless bird reports another one.
+const bgpPort = 179
+
+// Configured timers are not part of bird's show protocols output, bird's
+// default timer configuration is served as the configured timers.
+const (
+	bgpDefaultConnectRetryTime = 120
+	bgpDefaultHoldTime         = 240
+	bgpDefaultKeepaliveTime    = 80
+)
+
+// bgpPeerColumns are the served bgpPeerEntry columns in OID order.
+var bgpPeerColumns = []tableColumn[ProtocolBGPStatus]{
+	{oidBgpPeerIdentifier, pdu.VariableTypeIPAddress, func(proto ProtocolBGPStatus) interface{} {
+		return ipv4OrZero(proto.NeighborId)
+	}},
+	{oidBgpPeerState, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return bgpState(proto.State)
+	}},
+	{oidBgpPeerAdminStatus, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		// stop(1) for protocols disabled in bird, start(2) otherwise
+		if proto.ProtoState == "down" {
+			return int32(1)
+		}
+		return int32(2)
+	}},
+	{oidBgpPeerNegotiatedVersion, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		if proto.State == "Established" {
+			return int32(4)
+		}
+		return int32(0)
+	}},
+	{oidBgpPeerLocalAddr, pdu.VariableTypeIPAddress, func(proto ProtocolBGPStatus) interface{} {
+		return ipv4OrZero(proto.SourceAddress)
+	}},
+	{oidBgpPeerLocalPort, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		// bird does not report the local port of the session
+		return int32(0)
+	}},
+	{oidBgpPeerRemoteAddr, pdu.VariableTypeIPAddress, func(proto ProtocolBGPStatus) interface{} {
+		return proto.NeighborAddress.To4()
+	}},
+	{oidBgpPeerRemotePort, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return int32(remotePort(proto))
+	}},
+	{oidBgpPeerRemoteAs, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return int32(proto.NeighborAs)
+	}},
+	{oidBgpPeerLastError, pdu.VariableTypeOctetString, func(proto ProtocolBGPStatus) interface{} {
+		code, subcode := bgpLastErrorCode(proto.LastError)
+		return string([]byte{code, subcode})
+	}},
+	{oidBgpPeerFsmEstablishedTime, pdu.VariableTypeGauge32, func(proto ProtocolBGPStatus) interface{} {
+		if proto.Up {
+			return uint32(time.Since(proto.Since).Seconds())
+		}
+		return uint32(0)
+	}},
+	{oidBgpPeerConnectRetryInterval, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return int32(bgpDefaultConnectRetryTime)
+	}},
+	{oidBgpPeerHoldTime, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return int32(proto.HoldTime)
+	}},
+	{oidBgpPeerKeepAlive, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		return int32(proto.KeepaliveTime)
+	}},
+	{oidBgpPeerHoldTimeConfigured, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		// bird's default whatever the configuration, it isn't shown
+		return int32(bgpDefaultHoldTime)
+	}},
+	{oidBgpPeerKeepAliveConfigured, pdu.VariableTypeInteger, func(proto ProtocolBGPStatus) interface{} {
+		// bird's default whatever the configuration, it isn't shown
+		return int32(bgpDefaultKeepaliveTime)
+	}},
 }
 
-func (h *BirdBGPHandler) Refresh() error {
+func remotePort(proto ProtocolBGPStatus) int {
+	if proto.NeighborPort != 0 {
+		return proto.NeighborPort
+	}
+	return bgpPort
+}
 
-	h.bird.Write("show status")
-	showStatusString, err := h.bird.ReadString()
+func (h *BirdBGPHandler) Refresh(ctx context.Context) error {
+	cur := h.current()
+	showStatusString, err := h.conn.Command(ctx, "show status")
 	if err != nil {
+		h.republish(cur)
 		return err
 	}
 	status := ParseShowStatus(showStatusString)
 
-	h.bird.Write("show protocols all")
-	protocolsAllString, err := h.bird.ReadString()
+	protocolsAllString, err := h.conn.Command(ctx, "show protocols all")
 	if err != nil {
+		h.republish(cur)
 		return err
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	allProtocols := ParseShowProtocols(protocolsAllString)
 	protocols := ParseShowProtocolsAll(protocolsAllString)
-	h.birdT = time.Now().UTC()
-	h.data = &ListHandler{}
+	transitions := bgpPeerTransitions(cur.protocols, protocols)
+
+	now := time.Now()
+	h.rebuild(now, dataUpdated(cur, now, status, allProtocols, protocols), status, allProtocols, protocols)
+	h.notify(transitions)
+	return nil
+}
+
+// RefreshProtocols refreshes the daemon status and only the named
+// protocols with show protocols all <name>, an empty name refreshes
+// everything. Names of unknown protocols are ignored, new protocols are
+// found by a full refresh. The data age remains the one of the last full
+// refresh.
+func (h *BirdBGPHandler) RefreshProtocols(ctx context.Context, names []string) error {
+	cur := h.current()
+	known := map[string]bool{}
+	for _, proto := range cur.allProtocols {
+		known[proto.Name] = true
+	}
+	refresh := []string{}
+	for _, name := range names {
+		if name == "" {
+			return h.Refresh(ctx)
+		}
+		if known[name] {
+			refresh = append(refresh, name)
+			known[name] = false
+		}
+	}
+	if len(refresh) == 0 {
+		return nil
+	}
+
+	showStatusString, err := h.conn.Command(ctx, "show status")
+	if err != nil {
+		h.republish(cur)
+		return err
+	}
+	status := ParseShowStatus(showStatusString)
+
+	allProtocols, protocols, err := h.fetchProtocols(ctx, cur, refresh)
+	if err != nil {
+		h.republish(cur)
+		return err
+	}
+	transitions := bgpPeerTransitions(cur.protocols, protocols)
+
+	h.rebuild(cur.birdT, dataUpdated(cur, time.Now(), status, allProtocols, protocols), status, allProtocols, protocols)
+	h.notify(transitions)
+	return nil
+}
+
+// RefreshIncremental polls the show protocols summary and refreshes only
+// the protocols whose state, since timestamp or info changed, plus the ones
+// added or removed. Route counters of other protocols are left as they
+// are until the next full refresh.
+func (h *BirdBGPHandler) RefreshIncremental(ctx context.Context) error {
+	cur := h.current()
+	if cur.birdT.IsZero() {
+		return h.Refresh(ctx)
+	}
+	showStatusString, err := h.conn.Command(ctx, "show status")
+	if err != nil {
+		h.republish(cur)
+		return err
+	}
+	status := ParseShowStatus(showStatusString)
+
+	summaryString, err := h.conn.Command(ctx, "show protocols")
+	if err != nil {
+		h.republish(cur)
+		return err
+	}
+	changed := changedProtocols(cur.allProtocols, ParseShowProtocols(summaryString))
+
+	allProtocols, protocols, err := h.fetchProtocols(ctx, cur, changed)
+	if err != nil {
+		h.republish(cur)
+		return err
+	}
+	transitions := bgpPeerTransitions(cur.protocols, protocols)
+
+	now := time.Now()
+	h.rebuild(now, dataUpdated(cur, now, status, allProtocols, protocols), status, allProtocols, protocols)
+	h.notify(transitions)
+	return nil
+}
+
+// dataUpdated returns the time the data of a refresh at now last changed:
+// the update time of cur if the status, apart from bird's server time, and
+// the protocols are the same, now otherwise.
+func dataUpdated(cur *birdSnapshot, now time.Time, status ShowStatus, allProtocols []ProtocolStatus, protocols []ProtocolBGPStatus) time.Time {
+	if cur.updated.IsZero() {
+		return now
+	}
+	prev := cur.status
+	prev.ServerTime = status.ServerTime
+	if reflect.DeepEqual(prev, status) && reflect.DeepEqual(cur.allProtocols, allProtocols) && reflect.DeepEqual(cur.protocols, protocols) {
+		return cur.updated
+	}
+	return now
+}
+
+// changedProtocols returns the names of protocols added, removed or changed
+// between two show protocols summaries.
+func changedProtocols(prev []ProtocolStatus, cur []ProtocolStatus) []string {
+	prevByName := make(map[string]ProtocolStatus, len(prev))
+	for _, proto := range prev {
+		prevByName[proto.Name] = proto
+	}
+	changed := []string{}
+	for _, proto := range cur {
+		old, ok := prevByName[proto.Name]
+		delete(prevByName, proto.Name)
+		if !ok || old.State != proto.State || !old.Since.Equal(proto.Since) || old.Info != proto.Info {
+			changed = append(changed, proto.Name)
+		}
+	}
+	for name := range prevByName {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// fetchProtocols runs show protocols all <name> for every name and returns
+// the protocols of cur with the results merged in. Protocols bird doesn't
+// know anymore are removed.
+func (h *BirdBGPHandler) fetchProtocols(ctx context.Context, cur *birdSnapshot, names []string) ([]ProtocolStatus, []ProtocolBGPStatus, error) {
+	allProtocols := cur.allProtocols
+	protocols := cur.protocols
+	for _, name := range names {
+		out, err := h.conn.Command(ctx, "show protocols all "+name)
+		var replyErr *BirdReplyError
+		if errors.As(err, &replyErr) {
+			// removed by a reconfiguration
+			out, err = "", nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		allProtocols = mergeProtocols(allProtocols, ParseShowProtocols(out), name, func(proto ProtocolStatus) string { return proto.Name })
+		protocols = mergeProtocols(protocols, ParseShowProtocolsAll(out), name, func(proto ProtocolBGPStatus) string { return proto.Name })
+	}
+	return allProtocols, protocols, nil
+}
+
+// mergeProtocols returns protocols with the protocol called name replaced
+// by the ones in updated, or removed if updated is empty. A new protocol is
+// appended.
+func mergeProtocols[T any](protocols []T, updated []T, name string, nameOf func(T) string) []T {
+	merged := make([]T, 0, len(protocols)+len(updated))
+	replaced := false
+	for _, proto := range protocols {
+		if nameOf(proto) != name {
+			merged = append(merged, proto)
+			continue
+		}
+		if !replaced {
+			merged = append(merged, updated...)
+			replaced = true
+		}
+	}
+	if !replaced {
+		merged = append(merged, updated...)
+	}
+	return merged
+}
+
+// notify sends a notification for every peer transition, if enabled.
+func (h *BirdBGPHandler) notify(transitions []bgpPeerTransition) {
+	if h.Notifier == nil {
+		return
+	}
+	for _, transition := range transitions {
+		variables, ok := transition.variables()
+		if !ok {
+			continue
+		}
+		if err := h.Notifier.Notify(transition.trapOID, variables); err != nil {
+			log.Printf("[ERROR] Failed to send notification for %s: %v", transition.proto.Name, err)
+		}
+	}
+}
+
+// current returns the served snapshot, an empty one before the first
+// rebuild.
+func (h *BirdBGPHandler) current() *birdSnapshot {
+	if snapshot := h.snapshot.Load(); snapshot != nil {
+		return snapshot
+	}
+	return &birdSnapshot{data: &ListHandler{}}
+}
+
+// republish rebuilds the tree of cur, to keep serving its data with the
+// connection state updated after a failed refresh.
+func (h *BirdBGPHandler) republish(cur *birdSnapshot) {
+	h.rebuild(cur.birdT, cur.updated, cur.status, cur.allProtocols, cur.protocols)
+}
+
+// rebuild builds a tree from status, the protocols of every type and the
+// BGP protocols received at birdT and publishes it as the served snapshot.
+// Requests being served meanwhile keep using the previous snapshot.
+func (h *BirdBGPHandler) rebuild(birdT time.Time, updated time.Time, status ShowStatus, allProtocols []ProtocolStatus, protocols []ProtocolBGPStatus) {
+	snapshot := &birdSnapshot{
+		birdT:        birdT,
+		updated:      updated,
+		status:       status,
+		allProtocols: allProtocols,
+		protocols:    protocols,
+		data:         &ListHandler{},
+	}
+	data := snapshot.data
 
 	var item *agentx.ListItem
-	item = h.data.Add(oidBgpVersion)
+	item = data.Add(oidBgpVersion)
 	item.Type = pdu.VariableTypeOctetString
 	item.Value = "4"
 
-	item = h.data.Add(append(oidBgpLocalAs, 0))
+	item = data.Add(append(oidBgpLocalAs, 0))
 	item.Type = pdu.VariableTypeInteger
-	item.Value = int32(protocols[0].LocalAs)
-
-	for _, proto := range protocols {
-		item = h.data.Add(append(oidBgpPeerState, ipToOid(proto.NeighborAddress)...))
-		item.Type = pdu.VariableTypeInteger
-		item.Value = bgpStateToInt[proto.State]
-	}
-	for _, proto := range protocols {
-		item = h.data.Add(append(oidBgpPeerRemoteAddr, ipToOid(proto.NeighborAddress)...))
-		item.Type = pdu.VariableTypeIPAddress
-		item.Value = proto.NeighborAddress.To4()
+	item.Value = int32(0)
+	if len(protocols) > 0 {
+		item.Value = int32(protocols[0].LocalAs)
 	}
+
+	// BGP4-MIB tables are indexed by IPv4 address only, IPv6 peers are
+	// served by the BGP4V2-MIB tables below.
+	ipv4Peers := []tableRow[ProtocolBGPStatus]{}
 	for _, proto := range protocols {
-		item = h.data.Add(append(oidBgpPeerFsmEstablishedTime, ipToOid(proto.NeighborAddress)...))
-		item.Type = pdu.VariableTypeGauge32
-		if proto.Up {
-			item.Value = uint32(time.Since(proto.Since).Seconds())
-		} else {
-			item.Value = uint32(0)
+		if proto.NeighborAddress.To4() != nil {
+			ipv4Peers = append(ipv4Peers, tableRow[ProtocolBGPStatus]{index: ipToOid(proto.NeighborAddress), row: proto})
 		}
 	}
-	item = h.data.Add(append(oidBgpIdentifier, 0))
+
+	addTable(data, bgpPeerColumns, ipv4Peers)
+	item = data.Add(append(oidBgpIdentifier, 0))
 	item.Type = pdu.VariableTypeIPAddress
 	item.Value = status.RouterId.To4()
-	return nil
+
+	// subtrees in OID order, so most cells are appended in order
+	addBgp4V2PeerTable(data, status, protocols)
+	addCiscoBgp4Tables(data, protocols)
+	addJnxBgpM2Tables(data, &h.jnxPeerIndexes, protocols)
+	addBirdChannelTable(data, protocols)
+	addBirdProtocolTable(data, allProtocols)
+	addBirdStatusScalars(data, status)
+	if h.conn != nil {
+		addBirdConnectionScalars(data, h.conn.Status())
+	}
+	addBirdDataAgeScalars(data,
+		func() time.Duration { return h.dataAge(snapshot) },
+		func() bool { return h.stale(snapshot) })
+	data.Sort()
+
+	h.snapshot.Store(snapshot)
 }
 
-func (h *BirdBGPHandler) Register(priority byte, client *agentx.Client) error {
-	session, err := client.Session()
-	if err != nil {
-		return fmt.Errorf("failed to initialize agentx session: %w", err)
+// DefaultSubtrees are the subtrees registered unless configured otherwise,
+// the Juniper compatibility subtree oidJnxBgpM2 is opt-in.
+var DefaultSubtrees = []value.OID{oidBgp, oidBgp4V2, oidBird, oidCiscoBgp4}
+
+// Register registers the provided subtrees served by the handler, each one in
+// its own agentx session because a session holds a single registration. It
+// returns the sessions in the order of subtrees.
+func (h *BirdBGPHandler) Register(priority byte, client *agentx.Client, subtrees []value.OID) ([]*agentx.Session, error) {
+	sessions := make([]*agentx.Session, 0, len(subtrees))
+	for _, subtree := range subtrees {
+		session, err := client.Session()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize agentx session: %w", err)
+		}
+		session.Handler = h
+		if err := session.Register(priority, subtree); err != nil {
+			return nil, fmt.Errorf("failed to register agentx session for %s: %w", subtree, err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// dataAge returns the time since the last successful refresh, or since the
+// start of the agent if there was none.
+func (h *BirdBGPHandler) dataAge(snapshot *birdSnapshot) time.Duration {
+	if snapshot.birdT.IsZero() {
+		return time.Since(h.started)
 	}
-	session.Handler = h
-	if err := session.Register(priority, oidBgp); err != nil {
-		return fmt.Errorf("failed to register agentx session: %w", err)
+	return time.Since(snapshot.birdT)
+}
+
+// stale tells whether the snapshot is older than MaxDataAge.
+func (h *BirdBGPHandler) stale(snapshot *birdSnapshot) bool {
+	if h.MaxDataAge <= 0 {
+		return false
 	}
-	return nil
+	return snapshot.birdT.IsZero() || h.dataAge(snapshot) > h.MaxDataAge
 }
 
+// hidden tells whether oid must not be served as the snapshot is stale.
+func (h *BirdBGPHandler) hidden(snapshot *birdSnapshot, oid value.OID) bool {
+	return h.StalePolicy != StalePolicyFlag && !oidHasPrefix(oid, oidBirdAgent) && h.stale(snapshot)
+}
+
+// Get and GetNext answer a single search range from the snapshot served
+// at the time. AgentX sessions and the standalone agent answer whole
+// requests from a single generation through Pin instead.
 func (h *BirdBGPHandler) Get(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.data.Get(oid)
+	return (&pinnedHandler{h: h, snapshot: h.current()}).Get(oid)
 }
 
 func (h *BirdBGPHandler) GetNext(from value.OID, includeFrom bool, to value.OID) (value.OID, pdu.VariableType, interface{}, error) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	return (&pinnedHandler{h: h, snapshot: h.current()}).GetNext(from, includeFrom, to)
+}
+
+// pinnedHandler answers requests from a single snapshot.
+type pinnedHandler struct {
+	h        *BirdBGPHandler
+	snapshot *birdSnapshot
+}
+
+// Pin returns a handler answering from the snapshot served now, whatever
+// the refreshes publish afterwards.
+func (h *BirdBGPHandler) Pin() agentx.Handler {
+	return &pinnedHandler{h: h, snapshot: h.current()}
+}
+
+func (p *pinnedHandler) Get(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
+	h, snapshot := p.h, p.snapshot
+	if h.hidden(snapshot, oid) {
+		if h.StalePolicy == StalePolicyGenErr {
+			return nil, pdu.VariableTypeNoSuchObject, nil, errStaleData
+		}
+		return oid, pdu.VariableTypeNoSuchInstance, nil, nil
+	}
+	return snapshot.data.Get(oid)
+}
+
+func (p *pinnedHandler) GetNext(from value.OID, includeFrom bool, to value.OID) (value.OID, pdu.VariableType, interface{}, error) {
+	h, snapshot := p.h, p.snapshot
 	// log.Printf("[TRACE] request from=%v includeFrom=%v to=%v", from, includeFrom, to)
-	repOid, repType, repV, err := h.data.GetNext(from, includeFrom, to)
+	repOid, repType, repV, err := snapshot.data.GetNext(from, includeFrom, to)
+	if repOid != nil && h.hidden(snapshot, repOid) {
+		if h.StalePolicy == StalePolicyGenErr {
+			return nil, pdu.VariableTypeNoSuchObject, nil, errStaleData
+		}
+		// skip to the agent scalars, the only objects still served
+		if compareOids(repOid, oidBirdAgent) == -1 {
+			repOid, repType, repV, err = snapshot.data.GetNext(oidBirdAgent, false, to)
+		}
+		if repOid != nil && h.hidden(snapshot, repOid) {
+			repOid, repType, repV, err = nil, pdu.VariableTypeNoSuchObject, nil, nil
+		}
+	}
 	// log.Printf("[TRACE] response oid=%v type=%s value=%v err=%v", repOid, repType, repV, err)
 	return repOid, repType, repV, err
 }
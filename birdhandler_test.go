@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func newTestHandler(birdT time.Time, policy string) *BirdBGPHandler {
+	h := &BirdBGPHandler{started: time.Now(), MaxDataAge: time.Minute, StalePolicy: policy}
+	status := ParseShowStatus(StatusInDefault)
+	h.rebuild(birdT, birdT, status, ParseShowProtocols(showProtocolsAllDefault), ParseShowProtocolsAll(showProtocolsAllDefault))
+	return h
+}
+
+func TestBirdBGPHandlerStale(t *testing.T) {
+	bgpVersion := append(value.OID{}, oidBgpVersion...)
+	birdEnd := value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 2}
+	tests := []struct {
+		name      string
+		birdT     time.Time
+		policy    string
+		wantType  pdu.VariableType
+		wantErr   bool
+		wantStale int32
+	}{
+		{name: "fresh", birdT: time.Now(), policy: StalePolicyNoSuchInstance, wantType: pdu.VariableTypeOctetString, wantStale: 2},
+		{name: "never refreshed", policy: StalePolicyNoSuchInstance, wantType: pdu.VariableTypeNoSuchInstance, wantStale: 1},
+		{name: "stale flag", birdT: time.Now().Add(-time.Hour), policy: StalePolicyFlag, wantType: pdu.VariableTypeOctetString, wantStale: 1},
+		{name: "stale nosuchinstance", birdT: time.Now().Add(-time.Hour), policy: StalePolicyNoSuchInstance, wantType: pdu.VariableTypeNoSuchInstance, wantStale: 1},
+		{name: "stale generr", birdT: time.Now().Add(-time.Hour), policy: StalePolicyGenErr, wantErr: true, wantStale: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(tt.birdT, tt.policy)
+			_, gotType, _, err := h.Get(bgpVersion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && gotType != tt.wantType {
+				t.Errorf("Get() type = %v, want %v", gotType, tt.wantType)
+			}
+
+			// the agent scalars are served whatever the policy
+			_, _, gotStale, err := h.Get(append(append(value.OID{}, oidBirdDataStale...), 0))
+			if err != nil || gotStale != tt.wantStale {
+				t.Errorf("birdDataStale = %v, %v, want %v", gotStale, err, tt.wantStale)
+			}
+			if tt.policy == StalePolicyNoSuchInstance {
+				gotOid, _, _, _ := h.GetNext(oidBird, false, birdEnd)
+				if tt.wantStale == 1 && !oidHasPrefix(gotOid, oidBirdAgent) {
+					t.Errorf("GetNext() = %v, want the first agent scalar", gotOid)
+				}
+				if tt.wantStale == 2 && oidHasPrefix(gotOid, oidBirdAgent) {
+					t.Errorf("GetNext() = %v, want the first BIRD-MIB object", gotOid)
+				}
+			}
+		})
+	}
+}
+
+func TestBirdBGPHandlerRefreshProtocols(t *testing.T) {
+	xxxUp := `
+Name       Proto      Table      State  Since         Info
+xxx_gw1    BGP        ---        up     2024-10-13 10:00:00  Established
+  BGP state:          Established
+    Neighbor address: 192.168.32.253
+    Neighbor AS:      64846
+    Local AS:         64846
+`
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, map[string]string{
+		"show status":                  birdReply(StatusInDefault),
+		"show protocols all":           birdReply(showProtocolsAllDefault),
+		"show protocols all xxx_gw1":   birdReply(xxxUp),
+		"show protocols all direct1":   "8003 direct1: No such protocol\n",
+		"show protocols all unknown_1": birdReply(xxxUp),
+	})
+	defer bird.Close()
+
+	h := NewBirdBGPHandler(context.Background(), NewBirdConn(path, time.Second))
+	defer h.conn.Close()
+	prev := h.current()
+	if err := h.RefreshProtocols(context.Background(), []string{"xxx_gw1", "direct1", "unknown_1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := h.current()
+	if !cur.birdT.Equal(prev.birdT) || !cur.updated.After(prev.updated) {
+		t.Errorf("refresh times = %v/%v, want %v and a later update than %v", cur.birdT, cur.updated, prev.birdT, prev.updated)
+	}
+	var gotAll []string
+	for _, proto := range cur.allProtocols {
+		gotAll = append(gotAll, proto.Name+" "+proto.State)
+	}
+	wantAll := []string{"helpers up", "device1 up", "ber1_gw1 up", "xxx_gw1 up"}
+	if !reflect.DeepEqual(gotAll, wantAll) {
+		t.Errorf("protocols = %v, want %v", gotAll, wantAll)
+	}
+	var gotBgp []string
+	for _, proto := range cur.protocols {
+		gotBgp = append(gotBgp, proto.Name+" "+proto.State)
+	}
+	wantBgp := []string{"ber1_gw1 Established", "xxx_gw1 Established"}
+	if !reflect.DeepEqual(gotBgp, wantBgp) {
+		t.Errorf("BGP protocols = %v, want %v", gotBgp, wantBgp)
+	}
+	if cur.status.Hostname != "infra2" {
+		t.Errorf("status = %+v, want the status of the full refresh", cur.status)
+	}
+}
+
+func TestBirdBGPHandlerRefreshProtocolsStatus(t *testing.T) {
+	reconfigured := strings.Replace(StatusInDefault, "Last reconfiguration on 2024-10-13 09:25:06.844", "Last reconfiguration on 2024-10-13 14:39:40.000", 1)
+	for _, names := range [][]string{{""}, {"ber1_gw1"}} {
+		t.Run(strings.Join(names, ","), func(t *testing.T) {
+			recording := newBirdRecording(fakeBirdBanner)
+			recording.add("show status", birdReply(StatusInDefault))
+			recording.add("show status", birdReply(reconfigured))
+			recording.add("show protocols all", birdReply(showProtocolsAllDefault))
+			recording.add("show protocols all ber1_gw1", birdReply(showProtocolsAllDefault))
+			path := filepath.Join(t.TempDir(), "bird.ctl")
+			bird, err := NewFakeBird(path, recording)
+			if err != nil {
+				t.Fatal(err)
+			}
+			go bird.Serve(context.Background())
+			defer bird.Close()
+
+			h := NewBirdBGPHandler(context.Background(), NewBirdConn(path, time.Second))
+			defer h.conn.Close()
+			if err := h.RefreshProtocols(context.Background(), names); err != nil {
+				t.Fatal(err)
+			}
+			want := ParseShowStatus(reconfigured).LastReconfiguration
+			if got := h.current().status.LastReconfiguration; !got.Equal(want) {
+				t.Errorf("last reconfiguration = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBirdBGPHandlerRefreshIncremental(t *testing.T) {
+	summary := `
+Name       Proto      Table      State  Since         Info
+helpers    Static     master4    up     2024-10-12 20:41:10
+device1    Device     ---        up     2024-10-12 20:41:10
+ber1_gw1   BGP        ---        up     2024-10-12 20:41:14  Established
+xxx_gw1    BGP        ---        up     2024-10-13 10:00:00  Established
+static2    Static     master6    up     2024-10-13 10:00:00
+`
+	xxxUp := `
+Name       Proto      Table      State  Since         Info
+xxx_gw1    BGP        ---        up     2024-10-13 10:00:00  Established
+  BGP state:          Established
+    Neighbor address: 192.168.32.253
+    Neighbor AS:      64846
+    Local AS:         64846
+`
+	static2 := `
+Name       Proto      Table      State  Since         Info
+static2    Static     master6    up     2024-10-13 10:00:00
+`
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, map[string]string{
+		"show status":                "1000-BIRD 2.15.1\n" + birdReply(StatusInDefault),
+		"show protocols":             birdReply(summary),
+		"show protocols all":         birdReply(showProtocolsAllDefault),
+		"show protocols all xxx_gw1": birdReply(xxxUp),
+		"show protocols all static2": birdReply(static2),
+		"show protocols all direct1": "8003 direct1: No such protocol\n",
+	})
+	defer bird.Close()
+
+	h := NewBirdBGPHandler(context.Background(), NewBirdConn(path, time.Second))
+	defer h.conn.Close()
+	ber1 := h.current().protocols[0]
+	if err := h.RefreshIncremental(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := h.current()
+	var gotAll []string
+	for _, proto := range cur.allProtocols {
+		gotAll = append(gotAll, proto.Name+" "+proto.State)
+	}
+	wantAll := []string{"helpers up", "device1 up", "ber1_gw1 up", "xxx_gw1 up", "static2 up"}
+	if !reflect.DeepEqual(gotAll, wantAll) {
+		t.Errorf("protocols = %v, want %v", gotAll, wantAll)
+	}
+	if len(cur.protocols) != 2 || cur.protocols[1].State != "Established" {
+		t.Errorf("BGP protocols = %+v, want xxx_gw1 established", cur.protocols)
+	}
+	if !reflect.DeepEqual(cur.protocols[0], ber1) {
+		t.Errorf("unchanged protocol = %+v, want %+v", cur.protocols[0], ber1)
+	}
+}
+
+func TestBirdBGPHandlerRefreshUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, map[string]string{
+		"show status":        birdReply(StatusInDefault),
+		"show protocols all": birdReply(showProtocolsAllDefault),
+	})
+	defer bird.Close()
+
+	h := NewBirdBGPHandler(context.Background(), NewBirdConn(path, time.Second))
+	defer h.conn.Close()
+	if err := h.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	prev := h.current()
+	if err := h.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := h.current()
+	if !cur.birdT.After(prev.birdT) || !cur.updated.Equal(prev.updated) {
+		t.Errorf("refresh times = %v/%v, want a later refresh than %v and the update time %v", cur.birdT, cur.updated, prev.birdT, prev.updated)
+	}
+}
+
+func Test_bgpPeerConfiguredTimers(t *testing.T) {
+	// the peer offered lower timers, then the session went down
+	for _, proto := range []ProtocolBGPStatus{
+		{Name: "gw1", State: "Established", NeighborAddress: net.IPv4(192, 168, 32, 1), HoldTime: 90, KeepaliveTime: 30},
+		{Name: "gw1", State: "Active", NeighborAddress: net.IPv4(192, 168, 32, 1)},
+	} {
+		data := &ListHandler{}
+		addTable(data, bgpPeerColumns, []tableRow[ProtocolBGPStatus]{{index: value.OID{192, 168, 32, 1}, row: proto}})
+		for _, column := range []struct {
+			oid  value.OID
+			want int32
+		}{
+			{oidBgpPeerHoldTimeConfigured, 240},
+			{oidBgpPeerKeepAliveConfigured, 80},
+		} {
+			_, _, got, _ := data.Get(append(append(value.OID{}, column.oid...), 192, 168, 32, 1))
+			if got != column.want {
+				t.Errorf("%s %v = %v, want %d", proto.State, column.oid, got, column.want)
+			}
+		}
+	}
+}
+
+func Test_bgpState(t *testing.T) {
+	for state, want := range map[string]int32{"Established": 6, "Connect": 2, "Close": 1, "": 1, "Unknown": 1} {
+		if got := bgpState(state); got != want {
+			t.Errorf("bgpState(%q) = %d, want %d", state, got, want)
+		}
+	}
+}
+
+func Test_changedProtocols(t *testing.T) {
+	since := time.Date(2024, 10, 12, 20, 41, 10, 0, time.Local)
+	prev := []ProtocolStatus{
+		{Name: "same", State: "up", Since: since},
+		{Name: "state", State: "start", Since: since, Info: "Active"},
+		{Name: "info", State: "start", Since: since, Info: "Active"},
+		{Name: "removed", State: "up", Since: since},
+	}
+	cur := []ProtocolStatus{
+		{Name: "same", State: "up", Since: since},
+		{Name: "state", State: "up", Since: since.Add(time.Minute), Info: "Established"},
+		{Name: "info", State: "start", Since: since, Info: "Connect"},
+		{Name: "added", State: "up", Since: since},
+	}
+	want := []string{"added", "info", "removed", "state"}
+	if got := changedProtocols(prev, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedProtocols() = %v, want %v", got, want)
+	}
+}
+
+func TestBirdBGPHandlerSnapshot(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	status := ParseShowStatus(StatusInDefault)
+	protocols := ParseShowProtocolsAll(showProtocolsAllDefault)
+	localAs := append(append(value.OID{}, oidBgpLocalAs...), 0)
+
+	// every request is answered from a complete generation, either the one
+	// with all protocols or the one with none
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				h.rebuild(time.Now(), time.Now(), status, nil, nil)
+			} else {
+				h.rebuild(time.Now(), time.Now(), status, ParseShowProtocols(showProtocolsAllDefault), protocols)
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		_, _, got, err := h.Get(localAs)
+		if err != nil || (got != int32(0) && got != int32(protocols[0].LocalAs)) {
+			t.Fatalf("Get(bgpLocalAs) = %v, %v", got, err)
+		}
+		gotOid, _, _, err := h.GetNext(oidBgp, false, oidBgp4V2)
+		if err != nil || gotOid == nil {
+			t.Fatalf("GetNext() = %v, %v", gotOid, err)
+		}
+	}
+}
+
+// benchmarkProtocols returns n established peers with an IPv4 and an IPv6
+// channel each, alternating IPv4 and IPv6 neighbors.
+func TestBirdBGPHandlerAgentxPin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "master")
+	master := startFakeAgentxMaster(t, path, false)
+	defer master.listener.Close()
+	client, err := agentx.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Timeout = time.Minute
+
+	// reading the first object publishes another generation
+	first := value.OID{1, 3, 6, 1, 2, 1, 15, 1, 0}
+	second := value.OID{1, 3, 6, 1, 2, 1, 15, 2, 0}
+	h := &BirdBGPHandler{started: time.Now(), StalePolicy: StalePolicyFlag}
+	generation := func(n int32, refresh func() interface{}) *birdSnapshot {
+		data := &ListHandler{}
+		item := data.Add(first)
+		item.Type, item.Value = pdu.VariableTypeInteger, n
+		if refresh != nil {
+			item.Value = refresh
+		}
+		item = data.Add(second)
+		item.Type, item.Value = pdu.VariableTypeInteger, n
+		return &birdSnapshot{birdT: time.Now(), updated: time.Now(), data: data}
+	}
+	next := generation(2, nil)
+	h.snapshot.Store(generation(1, func() interface{} {
+		h.snapshot.Store(next)
+		return int32(1)
+	}))
+	if _, err := h.Register(127, client, []value.OID{oidBgp}); err != nil {
+		t.Fatal(err)
+	}
+	master.next(t, pdu.TypeRegister)
+
+	master.getNext(t, value.OID{1, 3, 6, 1, 2, 1, 15, 3}, first, second)
+	response := master.next(t, pdu.TypeResponse)
+	// the response payload starts with sysUpTime, error and index
+	got := agentxTestVariables(t, response.payload[8:])
+	if len(got) != 2 || got[0].Value != int32(1) || got[1].Value != int32(1) {
+		t.Errorf("GetNext() = %v, want both bindings from the first generation", got)
+	}
+	if h.current() != next {
+		t.Error("no generation published during the request")
+	}
+}
+
+func benchmarkProtocols(n int) ([]ProtocolStatus, []ProtocolBGPStatus) {
+	since := time.Now().Add(-time.Hour)
+	stats := RouteChangeStats{"received": 100, "rejected": 1, "filtered": 2, "ignored": 3, "accepted": 94}
+	var allProtocols []ProtocolStatus
+	var protocols []ProtocolBGPStatus
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("peer%d", i)
+		neighbor := net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))
+		if i%2 == 1 {
+			neighbor = net.ParseIP(fmt.Sprintf("2001:db8::%x", i))
+		}
+		proto := ProtocolBGPStatus{
+			Name: name, Table: "master4", Up: true, ProtoState: "up", Since: since, State: "Established",
+			NeighborAddress: neighbor, NeighborAs: uint32(65000 + i), NeighborId: net.IPv4(192, 0, byte(i>>8), byte(i)),
+			LocalAs: 64846, SourceAddress: net.IPv4(192, 168, 32, 79), HoldTime: 240, KeepaliveTime: 80,
+			Channels: map[string]ProtocolBGPChannel{},
+		}
+		for _, channel := range []ProtocolBGPChannel{{Name: "ipv4", Afi: 1, Safi: 1}, {Name: "ipv6", Afi: 2, Safi: 1}} {
+			channel.State = "UP"
+			channel.Imported, channel.Exported, channel.Preferred = 1000+i, 10, 900
+			channel.ImportUpdates, channel.ImportWithdraws, channel.ExportUpdates, channel.ExportWithdraws = stats, stats, stats, stats
+			channel.BGPNextHop = []net.IP{neighbor}
+			proto.Channels[channel.Name] = channel
+		}
+		protocols = append(protocols, proto)
+		allProtocols = append(allProtocols, ProtocolStatus{Name: name, Proto: "BGP", Table: "---", State: "up", Since: since, Info: "Established"})
+	}
+	return allProtocols, protocols
+}
+
+func BenchmarkBirdBGPHandlerRebuild(b *testing.B) {
+	status := ParseShowStatus(StatusInDefault)
+	for _, n := range []int{100, 1500} {
+		allProtocols, protocols := benchmarkProtocols(n)
+		b.Run(fmt.Sprintf("%d peers", n), func(b *testing.B) {
+			h := &BirdBGPHandler{started: time.Now(), StalePolicy: StalePolicyFlag}
+			for i := 0; i < b.N; i++ {
+				now := time.Now()
+				h.rebuild(now, now, status, allProtocols, protocols)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// bird2snmp has no private enterprise number, the BIRD-MIB lives under
+// net-snmp's playpen arc (NET-SNMP-MIB::netSnmpPlaypen) meanwhile.
+var (
+	oidBird                       = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1}
+	oidBirdChannelName            = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 3}
+	oidBirdChannelState           = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 4}
+	oidBirdChannelRoutingTable    = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 5}
+	oidBirdChannelPreference      = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 6}
+	oidBirdChannelInputFilter     = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 7}
+	oidBirdChannelOutputFilter    = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 8}
+	oidBirdChannelImportedRoutes  = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 9}
+	oidBirdChannelFilteredRoutes  = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 10}
+	oidBirdChannelExportedRoutes  = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 11}
+	oidBirdChannelPreferredRoutes = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 12}
+	oidBirdChannelBgpNextHopType  = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 13}
+	oidBirdChannelBgpNextHop      = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 1, 1, 14}
+	oidBirdRouteChangeEntry       = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 2, 1}
+	oidBirdProtocolType           = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 3, 1, 2}
+	oidBirdProtocolRoutingTable   = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 3, 1, 3}
+	oidBirdProtocolState          = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 3, 1, 4}
+	oidBirdProtocolSince          = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 3, 1, 5}
+	oidBirdProtocolInfo           = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 3, 1, 6}
+	oidBirdVersion                = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 4, 1}
+	oidBirdUptime                 = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 4, 2}
+	oidBirdReconfigurationAge     = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 4, 3}
+	oidBirdDaemonState            = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 4, 4}
+	oidBirdAgent                  = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5}
+	oidBirdConnectionState        = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5, 1}
+	oidBirdConnectionReconnects   = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5, 2}
+	oidBirdConnectionLastError    = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5, 3}
+	oidBirdDataAge                = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5, 4}
+	oidBirdDataStale              = value.OID{1, 3, 6, 1, 4, 1, 8072, 9999, 9999, 1, 1, 5, 5}
+)
+
+var birdChannelStateToInt = map[string]int32{
+	"DOWN":     1,
+	"START":    2,
+	"UP":       3,
+	"FLUSHING": 4,
+}
+
+// birdChannel is a row of birdChannelTable.
+type birdChannel struct {
+	proto   ProtocolBGPStatus
+	channel ProtocolBGPChannel
+}
+
+// birdChannelColumns are the served birdChannelEntry columns in OID order.
+var birdChannelColumns = []tableColumn[birdChannel]{
+	{oidBirdChannelName, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+		return row.channel.Name
+	}},
+	{oidBirdChannelState, pdu.VariableTypeInteger, func(row birdChannel) interface{} {
+		return birdChannelStateToInt[row.channel.State]
+	}},
+	{oidBirdChannelRoutingTable, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+		return row.channel.Table
+	}},
+	{oidBirdChannelPreference, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+		return uint32(row.channel.Preference)
+	}},
+	{oidBirdChannelInputFilter, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+		return row.channel.InputFilter
+	}},
+	{oidBirdChannelOutputFilter, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+		return row.channel.OutputFilter
+	}},
+	{oidBirdChannelImportedRoutes, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+		return uint32(row.channel.Imported)
+	}},
+	{oidBirdChannelFilteredRoutes, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+		return uint32(row.channel.Filtered)
+	}},
+	{oidBirdChannelExportedRoutes, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+		return uint32(row.channel.Exported)
+	}},
+	{oidBirdChannelPreferredRoutes, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+		return uint32(row.channel.Preferred)
+	}},
+	{oidBirdChannelBgpNextHopType, pdu.VariableTypeInteger, func(row birdChannel) interface{} {
+		addrType, _ := inetAddress(channelNextHop(row.channel), "")
+		return addrType
+	}},
+	{oidBirdChannelBgpNextHop, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+		_, addr := inetAddress(channelNextHop(row.channel), "")
+		return string(addr)
+	}},
+}
+
+func channelNextHop(channel ProtocolBGPChannel) net.IP {
+	if len(channel.BGPNextHop) == 0 {
+		return nil
+	}
+	return channel.BGPNextHop[0]
+}
+
+// birdChannels returns the channels of all protocols indexed by protocol
+// name, AFI and SAFI. Channels of unknown address families are skipped.
+func birdChannels(protocols []ProtocolBGPStatus) []tableRow[birdChannel] {
+	rows := []tableRow[birdChannel]{}
+	for _, proto := range protocols {
+		for _, channel := range proto.Channels {
+			if channel.Afi == 0 {
+				continue
+			}
+			index := append(stringToOid(proto.Name), uint32(channel.Afi), uint32(channel.Safi))
+			rows = append(rows, tableRow[birdChannel]{index: index, row: birdChannel{proto: proto, channel: channel}})
+		}
+	}
+	return rows
+}
+
+func addBirdChannelTable(data *ListHandler, protocols []ProtocolBGPStatus) {
+	channels := birdChannels(protocols)
+	addTable(data, birdChannelColumns, channels)
+	addTable(data, birdRouteChangeColumns(), birdRouteChanges(channels))
+}
+
+// birdRouteChangeTypes are the rows of bird's route change stats matrix
+// in the order of their birdRouteChangeType index.
+var birdRouteChangeTypes = []func(channel ProtocolBGPChannel) RouteChangeStats{
+	func(channel ProtocolBGPChannel) RouteChangeStats { return channel.ImportUpdates },
+	func(channel ProtocolBGPChannel) RouteChangeStats { return channel.ImportWithdraws },
+	func(channel ProtocolBGPChannel) RouteChangeStats { return channel.ExportUpdates },
+	func(channel ProtocolBGPChannel) RouteChangeStats { return channel.ExportWithdraws },
+}
+
+// birdRouteChangeColumns are the served birdRouteChangeEntry columns, the
+// Counter64 columns 2-6 followed by their Counter32 counterparts 7-11 for
+// SNMPv1 pollers. Cells bird prints as "---" are absent.
+func birdRouteChangeColumns() []tableColumn[RouteChangeStats] {
+	columns := []tableColumn[RouteChangeStats]{}
+	for i, name := range routeChangeColumns {
+		name := name
+		columns = append(columns, tableColumn[RouteChangeStats]{
+			append(oidBirdRouteChangeEntry, uint32(2+i)), pdu.VariableTypeCounter64, func(stats RouteChangeStats) interface{} {
+				if v, ok := stats[name]; ok {
+					return v
+				}
+				return nil
+			},
+		})
+	}
+	for i, name := range routeChangeColumns {
+		name := name
+		columns = append(columns, tableColumn[RouteChangeStats]{
+			append(oidBirdRouteChangeEntry, uint32(2+len(routeChangeColumns)+i)), pdu.VariableTypeCounter32, func(stats RouteChangeStats) interface{} {
+				if v, ok := stats[name]; ok {
+					return uint32(v)
+				}
+				return nil
+			},
+		})
+	}
+	return columns
+}
+
+// birdRouteChanges returns the route change stats of every channel row
+// indexed by the channel index and birdRouteChangeType.
+func birdRouteChanges(channels []tableRow[birdChannel]) []tableRow[RouteChangeStats] {
+	rows := []tableRow[RouteChangeStats]{}
+	for _, channel := range channels {
+		for i, stats := range birdRouteChangeTypes {
+			if s := stats(channel.row.channel); s != nil {
+				index := append(append(value.OID{}, channel.index...), uint32(1+i))
+				rows = append(rows, tableRow[RouteChangeStats]{index: index, row: s})
+			}
+		}
+	}
+	return rows
+}
+
+var birdProtocolStateToInt = map[string]int32{
+	"down":  1,
+	"start": 2,
+	"up":    3,
+	"stop":  4,
+	"flush": 5,
+}
+
+// birdProtocolColumns are the served birdProtocolEntry columns in OID order.
+var birdProtocolColumns = []tableColumn[ProtocolStatus]{
+	{oidBirdProtocolType, pdu.VariableTypeOctetString, func(proto ProtocolStatus) interface{} {
+		return proto.Proto
+	}},
+	{oidBirdProtocolRoutingTable, pdu.VariableTypeOctetString, func(proto ProtocolStatus) interface{} {
+		return proto.Table
+	}},
+	{oidBirdProtocolState, pdu.VariableTypeInteger, func(proto ProtocolStatus) interface{} {
+		return birdProtocolStateToInt[proto.State]
+	}},
+	{oidBirdProtocolSince, pdu.VariableTypeOctetString, func(proto ProtocolStatus) interface{} {
+		if since := dateAndTime(proto.Since); since != nil {
+			return string(since)
+		}
+		return nil
+	}},
+	{oidBirdProtocolInfo, pdu.VariableTypeOctetString, func(proto ProtocolStatus) interface{} {
+		return proto.Info
+	}},
+}
+
+// addBirdProtocolTable adds a birdProtocolTable row for every protocol,
+// whatever its type, indexed by protocol name.
+func addBirdProtocolTable(data *ListHandler, protocols []ProtocolStatus) {
+	rows := []tableRow[ProtocolStatus]{}
+	for _, proto := range protocols {
+		rows = append(rows, tableRow[ProtocolStatus]{index: stringToOid(proto.Name), row: proto})
+	}
+	addTable(data, birdProtocolColumns, rows)
+}
+
+// birdDaemonState returns the birdDaemonState enum for status: running(1),
+// reconfiguring(2), shuttingDown(3), gracefulRestart(4) or unknown(0).
+func birdDaemonState(status ShowStatus) int32 {
+	switch status.DaemonState {
+	case "Daemon is up and running":
+		if status.GracefulRestart {
+			return 4
+		}
+		return 1
+	case "Reconfiguration in progress":
+		return 2
+	case "Shutdown in progress":
+		return 3
+	}
+	return 0
+}
+
+// addBirdStatusScalars adds the daemon status objects. Ages are computed
+// from bird's own server time, so they don't depend on both clocks or
+// time zones matching.
+func addBirdStatusScalars(data *ListHandler, status ShowStatus) {
+	var item *agentx.ListItem
+	item = data.Add(append(oidBirdVersion, 0))
+	item.Type = pdu.VariableTypeOctetString
+	item.Value = status.Version
+
+	if !status.ServerTime.IsZero() && !status.LastReboot.IsZero() {
+		item = data.Add(append(oidBirdUptime, 0))
+		item.Type = pdu.VariableTypeTimeTicks
+		item.Value = status.ServerTime.Sub(status.LastReboot)
+	}
+
+	if !status.ServerTime.IsZero() && !status.LastReconfiguration.IsZero() {
+		item = data.Add(append(oidBirdReconfigurationAge, 0))
+		item.Type = pdu.VariableTypeGauge32
+		item.Value = uint32(status.ServerTime.Sub(status.LastReconfiguration).Seconds())
+	}
+
+	item = data.Add(append(oidBirdDaemonState, 0))
+	item.Type = pdu.VariableTypeInteger
+	item.Value = birdDaemonState(status)
+}
+
+// addBirdConnectionScalars adds the state of the bird control socket
+// connection of the agent.
+func addBirdConnectionScalars(data *ListHandler, conn BirdConnStatus) {
+	var item *agentx.ListItem
+	item = data.Add(append(oidBirdConnectionState, 0))
+	item.Type = pdu.VariableTypeInteger
+	item.Value = int32(conn.State)
+
+	item = data.Add(append(oidBirdConnectionReconnects, 0))
+	item.Type = pdu.VariableTypeCounter32
+	item.Value = conn.Reconnects
+
+	item = data.Add(append(oidBirdConnectionLastError, 0))
+	item.Type = pdu.VariableTypeOctetString
+	item.Value = conn.LastError
+}
+
+// addBirdDataAgeScalars adds the age of the served data and whether it is
+// stale, both computed on request.
+func addBirdDataAgeScalars(data *ListHandler, age func() time.Duration, stale func() bool) {
+	var item *agentx.ListItem
+	item = data.Add(append(oidBirdDataAge, 0))
+	item.Type = pdu.VariableTypeGauge32
+	item.Value = func() interface{} {
+		return uint32(age().Seconds())
+	}
+
+	item = data.Add(append(oidBirdDataStale, 0))
+	item.Type = pdu.VariableTypeInteger
+	item.Value = func() interface{} {
+		// TruthValue
+		if stale() {
+			return int32(1)
+		}
+		return int32(2)
+	}
+}
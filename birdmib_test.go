@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func Test_birdDaemonState(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ShowStatus
+		want   int32
+	}{
+		{name: "running", status: ParseShowStatus(StatusInDefault), want: 1},
+		{name: "graceful restart", status: ParseShowStatus(StatusInGracefulRestart), want: 4},
+		{name: "shutdown", status: ParseShowStatus(StatusInShutdown), want: 3},
+		{name: "reconfiguring", status: ShowStatus{DaemonState: "Reconfiguration in progress"}, want: 2},
+		{name: "unknown", status: ShowStatus{}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := birdDaemonState(tt.status); got != tt.want {
+				t.Errorf("birdDaemonState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+// Nagios plugin return codes.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStateNames = [...]string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// CheckCmd is a Nagios and Icinga plugin checking the BGP sessions and the
+// daemon state of a single refresh.
+type CheckCmd struct {
+	Peers                 []string       `help:"BGP protocols to check, all if empty"`
+	MinUptime             time.Duration  `help:"warn about sessions established for less than this, 0 disables"`
+	MinImported           map[string]int `help:"critical if the named channel of a checked peer imports fewer routes, e.g. ipv4=900000" placeholder:"CHANNEL=ROUTES"`
+	MaxImported           map[string]int `help:"critical if the named channel of a checked peer imports more routes, e.g. ipv6=250000" placeholder:"CHANNEL=ROUTES"`
+	RecentReconfiguration time.Duration  `help:"warn if bird was reconfigured less than this ago, 0 disables"`
+}
+
+// checkStatus is the plugin state returned by CheckCmd.Run when not OK,
+// main exits with it.
+type checkStatus int
+
+func (s checkStatus) Error() string {
+	return checkStateNames[s]
+}
+
+func (c *CheckCmd) Run(ctx context.Context) error {
+	// plugins report through their first line of output only
+	log.SetOutput(io.Discard)
+	snapshot, err := refreshOnce(ctx)
+	if err != nil {
+		fmt.Printf("BIRD %s - %v\n", checkStateNames[checkUnknown], err)
+		return checkStatus(checkUnknown)
+	}
+	state, output := c.evaluate(snapshot.status, snapshot.protocols, time.Now())
+	fmt.Println(output)
+	if state != checkOK {
+		return checkStatus(state)
+	}
+	return nil
+}
+
+// isCheckParseError tells whether err is a command line error of the check
+// command, which plugins report as UNKNOWN rather than kong's exit status 1,
+// WARNING.
+func isCheckParseError(err error) bool {
+	var parseErr *kong.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Context == nil {
+		return false
+	}
+	selected := parseErr.Context.Selected()
+	return selected != nil && selected.Name == "check"
+}
+
+// checkResult collects the problems found and the performance data.
+type checkResult struct {
+	state    int
+	problems [checkUnknown + 1][]string
+	perfdata []string
+}
+
+func (r *checkResult) problem(state int, format string, args ...interface{}) {
+	r.state = max(r.state, state)
+	r.problems[state] = append(r.problems[state], fmt.Sprintf(format, args...))
+}
+
+// evaluate checks status and the BGP protocols at now and returns the
+// plugin state and its output: the summary, problems first, and the
+// perfdata of every checked peer.
+func (c *CheckCmd) evaluate(status ShowStatus, protocols []ProtocolBGPStatus, now time.Time) (int, string) {
+	r := &checkResult{}
+
+	switch birdDaemonState(status) {
+	case 1:
+	case 3:
+		r.problem(checkCritical, "bird is shutting down")
+	default:
+		r.problem(checkWarning, "bird: %s", status.DaemonState)
+	}
+	if c.RecentReconfiguration > 0 && !status.ServerTime.IsZero() && !status.LastReconfiguration.IsZero() {
+		if age := status.ServerTime.Sub(status.LastReconfiguration); age < c.RecentReconfiguration {
+			r.problem(checkWarning, "bird reconfigured %s ago", age.Truncate(time.Second))
+		}
+	}
+
+	checked, total := protocols, len(protocols)
+	if len(c.Peers) > 0 {
+		total = len(c.Peers)
+		checked = nil
+		for _, name := range c.Peers {
+			found := false
+			for _, proto := range protocols {
+				if proto.Name == name {
+					checked = append(checked, proto)
+					found = true
+					break
+				}
+			}
+			if !found {
+				r.problem(checkCritical, "%s not found", name)
+			}
+		}
+	}
+
+	established := 0
+	for _, proto := range checked {
+		uptime := time.Duration(0)
+		if proto.State == "Established" {
+			established++
+			uptime = now.Sub(proto.Since)
+			if c.MinUptime > 0 && uptime < c.MinUptime {
+				r.problem(checkWarning, "%s established %s ago", proto.Name, uptime.Truncate(time.Second))
+			}
+		} else {
+			r.problem(checkCritical, "%s is %s", proto.Name, proto.State)
+		}
+		r.perfdata = append(r.perfdata, checkPerfdata(proto.Name+"_uptime", fmt.Sprintf("%ds", int64(uptime.Seconds())), checkRange(int(c.MinUptime.Seconds()), 0), ""))
+
+		for _, name := range sortedChannelNames(proto) {
+			imported := proto.Channels[name].Imported
+			minImported, maxImported := c.MinImported[name], c.MaxImported[name]
+			// a session down is reported on its own
+			switch {
+			case proto.State != "Established":
+			case minImported > 0 && imported < minImported:
+				r.problem(checkCritical, "%s %s imports %d < %d routes", proto.Name, name, imported, minImported)
+			case maxImported > 0 && imported > maxImported:
+				r.problem(checkCritical, "%s %s imports %d > %d routes", proto.Name, name, imported, maxImported)
+			}
+			r.perfdata = append(r.perfdata, checkPerfdata(proto.Name+"_"+name+"_imported", fmt.Sprint(imported), "", checkRange(minImported, maxImported)))
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d peers established", established, total)
+	var problems []string
+	for state := checkCritical; state > checkOK; state-- {
+		problems = append(problems, r.problems[state]...)
+	}
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ") + ", " + summary
+	}
+	output := fmt.Sprintf("BIRD %s - %s", checkStateNames[r.state], summary)
+	if len(r.perfdata) > 0 {
+		output += " | " + strings.Join(r.perfdata, " ")
+	}
+	return r.state, output
+}
+
+// checkRange returns the plugin range alerting outside low to high, 0
+// being no limit.
+func checkRange(low int, high int) string {
+	switch {
+	case low > 0 && high > 0:
+		return fmt.Sprintf("%d:%d", low, high)
+	case low > 0:
+		return fmt.Sprintf("%d:", low)
+	case high > 0:
+		return fmt.Sprint(high)
+	}
+	return ""
+}
+
+// checkPerfdata formats a performance data item, quoting the label if
+// needed.
+func checkPerfdata(label string, value string, warn string, crit string) string {
+	if strings.ContainsAny(label, " '=") {
+		label = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	return strings.TrimRight(fmt.Sprintf("%s=%s;%s;%s", label, value, warn, crit), ";")
+}
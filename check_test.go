@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestCheckCmdEvaluate(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	snapshot := h.current()
+	// ber1_gw1 is established for an hour, xxx_gw1 is active
+	now := snapshot.protocols[0].Since.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		check     CheckCmd
+		wantState int
+		want      string
+	}{
+		{
+			name:      "peer down",
+			wantState: checkCritical,
+			want:      "BIRD CRITICAL - xxx_gw1 is Active, 1 of 2 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21 xxx_gw1_uptime=0s xxx_gw1_ipv4_imported=0",
+		},
+		{
+			name:      "ok",
+			check:     CheckCmd{Peers: []string{"ber1_gw1"}, MinImported: map[string]int{"ipv4": 1}, MaxImported: map[string]int{"ipv4": 100}},
+			wantState: checkOK,
+			want:      "BIRD OK - 1 of 1 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21;;1:100",
+		},
+		{
+			name:      "min uptime",
+			check:     CheckCmd{Peers: []string{"ber1_gw1"}, MinUptime: 2 * time.Hour},
+			wantState: checkWarning,
+			want:      "BIRD WARNING - ber1_gw1 established 1h0m0s ago, 1 of 1 peers established | ber1_gw1_uptime=3600s;7200: ber1_gw1_ipv4_imported=21",
+		},
+		{
+			name:      "imported routes",
+			check:     CheckCmd{MinImported: map[string]int{"ipv4": 50, "ipv6": 10}},
+			wantState: checkCritical,
+			want:      "BIRD CRITICAL - ber1_gw1 ipv4 imports 21 < 50 routes, xxx_gw1 is Active, 1 of 2 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21;;50: xxx_gw1_uptime=0s xxx_gw1_ipv4_imported=0;;50:",
+		},
+		{
+			name:      "other channel",
+			check:     CheckCmd{Peers: []string{"ber1_gw1"}, MinImported: map[string]int{"ipv6": 50}},
+			wantState: checkOK,
+			want:      "BIRD OK - 1 of 1 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21",
+		},
+		{
+			name:      "unknown peer",
+			check:     CheckCmd{Peers: []string{"ber1_gw1", "nope"}, MaxImported: map[string]int{"ipv4": 10}, MinUptime: 2 * time.Hour},
+			wantState: checkCritical,
+			want:      "BIRD CRITICAL - nope not found, ber1_gw1 ipv4 imports 21 > 10 routes, ber1_gw1 established 1h0m0s ago, 1 of 2 peers established | ber1_gw1_uptime=3600s;7200: ber1_gw1_ipv4_imported=21;;10",
+		},
+		{
+			// bird's own clock says the last reconfiguration was 5h14m ago
+			name:      "recent reconfiguration",
+			check:     CheckCmd{Peers: []string{"ber1_gw1"}, RecentReconfiguration: 6 * time.Hour},
+			wantState: checkWarning,
+			want:      "BIRD WARNING - bird reconfigured 5h14m33s ago, 1 of 1 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, got := tt.check.evaluate(snapshot.status, snapshot.protocols, now)
+			if state != tt.wantState || got != tt.want {
+				t.Errorf("evaluate() = %d %q\nwant %d %q", state, got, tt.wantState, tt.want)
+			}
+		})
+	}
+
+	t.Run("session not established", func(t *testing.T) {
+		protocols := append([]ProtocolBGPStatus{}, snapshot.protocols...)
+		protocols[0].State = "OpenConfirm"
+		state, got := (&CheckCmd{Peers: []string{"ber1_gw1"}, MinImported: map[string]int{"ipv4": 50}}).evaluate(snapshot.status, protocols, now)
+		if want := "BIRD CRITICAL - ber1_gw1 is OpenConfirm, 0 of 1 peers established | ber1_gw1_uptime=0s ber1_gw1_ipv4_imported=21;;50:"; state != checkCritical || got != want {
+			t.Errorf("evaluate() = %d %q, want %q", state, got, want)
+		}
+	})
+
+	t.Run("daemon state", func(t *testing.T) {
+		status := snapshot.status
+		status.DaemonState = "Reconfiguration in progress"
+		state, got := (&CheckCmd{Peers: []string{"ber1_gw1"}}).evaluate(status, snapshot.protocols, now)
+		if want := "BIRD WARNING - bird: Reconfiguration in progress, 1 of 1 peers established | ber1_gw1_uptime=3600s ber1_gw1_ipv4_imported=21"; state != checkWarning || got != want {
+			t.Errorf("evaluate() = %d %q, want %q", state, got, want)
+		}
+	})
+}
+
+func Test_isCheckParseError(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    bool
+		wantMin map[string]int
+	}{
+		{args: []string{"check", "--min-imported", "ipv4=900000", "--min-imported", "ipv6=190000"}, wantMin: map[string]int{"ipv4": 900000, "ipv6": 190000}},
+		{args: []string{"check", "--min-imported", "ipv4=900000;ipv6=190000"}, wantMin: map[string]int{"ipv4": 900000, "ipv6": 190000}},
+		{args: []string{"check", "--min-imported", "900000"}, want: true},
+		{args: []string{"-s", "/run/bird.ctl", "check", "--unknown"}, want: true},
+		{args: []string{"walk", "--unknown"}, want: false},
+		{args: []string{"--unknown"}, want: false},
+	}
+	for _, tt := range tests {
+		cli := CLI
+		parser, err := kong.New(&cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = parser.Parse(tt.args)
+		if got := isCheckParseError(err); got != tt.want {
+			t.Errorf("isCheckParseError(%v) = %v, want %v", err, got, tt.want)
+		}
+		if tt.wantMin != nil && !reflect.DeepEqual(cli.Check.MinImported, tt.wantMin) {
+			t.Errorf("%v: --min-imported = %v, want %v", tt.args, cli.Check.MinImported, tt.wantMin)
+		}
+	}
+}
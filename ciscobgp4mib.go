@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// CISCO-BGP4-MIB per address family prefix counters, served for pollers
+// that only know the Cisco tables.
+var (
+	oidCiscoBgp4                   = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187}
+	oidCbgpPeerAddrFamilyName      = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 3, 1, 3}
+	oidCbgpPeerAcceptedPrefixes    = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 4, 1, 1}
+	oidCbgpPeerDeniedPrefixes      = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 4, 1, 2}
+	oidCbgpPeerPrefixAdminLimit    = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 4, 1, 3}
+	oidCbgpPeerAdvertisedPrefixes  = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 4, 1, 6}
+	oidCbgpPeer2AddrFamilyName     = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 7, 1, 3}
+	oidCbgpPeer2AcceptedPrefixes   = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 8, 1, 1}
+	oidCbgpPeer2DeniedPrefixes     = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 8, 1, 2}
+	oidCbgpPeer2PrefixAdminLimit   = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 8, 1, 3}
+	oidCbgpPeer2AdvertisedPrefixes = value.OID{1, 3, 6, 1, 4, 1, 9, 9, 187, 1, 2, 8, 1, 6}
+)
+
+// cbgpAddrFamilyColumns returns the served columns of an address family
+// table and its prefix table, which are the same for both peer tables.
+func cbgpAddrFamilyColumns(name, accepted, denied, adminLimit, advertised value.OID) []tableColumn[birdChannel] {
+	return []tableColumn[birdChannel]{
+		{name, pdu.VariableTypeOctetString, func(row birdChannel) interface{} {
+			return row.channel.Name
+		}},
+		{accepted, pdu.VariableTypeCounter32, func(row birdChannel) interface{} {
+			return uint32(row.channel.Imported)
+		}},
+		{denied, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+			// bird counts the routes currently filtered, not the denials
+			return uint32(row.channel.Filtered)
+		}},
+		{adminLimit, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+			if row.channel.ImportLimit == 0 {
+				return nil
+			}
+			return uint32(row.channel.ImportLimit)
+		}},
+		{advertised, pdu.VariableTypeGauge32, func(row birdChannel) interface{} {
+			return uint32(row.channel.Exported)
+		}},
+	}
+}
+
+var (
+	cbgpPeerAddrFamilyColumns = cbgpAddrFamilyColumns(
+		oidCbgpPeerAddrFamilyName, oidCbgpPeerAcceptedPrefixes, oidCbgpPeerDeniedPrefixes,
+		oidCbgpPeerPrefixAdminLimit, oidCbgpPeerAdvertisedPrefixes,
+	)
+	cbgpPeer2AddrFamilyColumns = cbgpAddrFamilyColumns(
+		oidCbgpPeer2AddrFamilyName, oidCbgpPeer2AcceptedPrefixes, oidCbgpPeer2DeniedPrefixes,
+		oidCbgpPeer2PrefixAdminLimit, oidCbgpPeer2AdvertisedPrefixes,
+	)
+)
+
+// cbgpPeerAddrFamilies returns the channels of IPv4 peers indexed by
+// bgpPeerRemoteAddr, AFI and SAFI as cbgpPeerAddrFamilyTable does, and the
+// channels of all peers indexed by address type, address, AFI and SAFI as
+// cbgpPeer2AddrFamilyTable does.
+func cbgpPeerAddrFamilies(protocols []ProtocolBGPStatus) ([]tableRow[birdChannel], []tableRow[birdChannel]) {
+	rows := []tableRow[birdChannel]{}
+	rows2 := []tableRow[birdChannel]{}
+	for _, proto := range protocols {
+		addrType, addr := inetAddress(proto.NeighborAddress, proto.NeighborInterface)
+		if addr == nil {
+			continue
+		}
+		for _, channel := range proto.Channels {
+			if channel.Afi == 0 {
+				continue
+			}
+			row := birdChannel{proto: proto, channel: channel}
+			afiSafi := []uint32{uint32(channel.Afi), uint32(channel.Safi)}
+			if addrType == inetAddressTypeIPv4 {
+				index := append(ipToOid(proto.NeighborAddress), afiSafi...)
+				rows = append(rows, tableRow[birdChannel]{index: index, row: row})
+			}
+			index := append(inetAddressToOid(addrType, addr), afiSafi...)
+			rows2 = append(rows2, tableRow[birdChannel]{index: index, row: row})
+		}
+	}
+	return rows, rows2
+}
+
+func addCiscoBgp4Tables(data *ListHandler, protocols []ProtocolBGPStatus) {
+	rows, rows2 := cbgpPeerAddrFamilies(protocols)
+	addTable(data, cbgpPeerAddrFamilyColumns, rows)
+	addTable(data, cbgpPeer2AddrFamilyColumns, rows2)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_addCiscoBgp4Tables(t *testing.T) {
+	protocols := []ProtocolBGPStatus{{
+		Name:            "gw1",
+		NeighborAddress: net.ParseIP("192.168.32.1"),
+		Channels: map[string]ProtocolBGPChannel{
+			"ipv4": {Name: "ipv4", Afi: 1, Safi: 1, Imported: 10, Filtered: 2, Exported: 5, ImportLimit: 1000},
+			"ipv6": {Name: "ipv6", Afi: 2, Safi: 1, Imported: 3},
+		},
+	}, {
+		Name:            "gw2",
+		NeighborAddress: net.ParseIP("2001:db8::1"),
+		Channels: map[string]ProtocolBGPChannel{
+			"ipv6": {Name: "ipv6", Afi: 2, Safi: 1, Imported: 7, Filtered: 1, Exported: 4},
+		},
+	}}
+	data := &ListHandler{}
+	addCiscoBgp4Tables(data, protocols)
+
+	// cbgpPeerAddrFamilyTable: bgpPeerRemoteAddr, AFI and SAFI
+	ipv4 := value.OID{192, 168, 32, 1, 1, 1}
+	ipv4v6 := value.OID{192, 168, 32, 1, 2, 1}
+	// cbgpPeer2AddrFamilyTable: InetAddressType, length, address, AFI and SAFI
+	peer2ipv4 := value.OID{1, 4, 192, 168, 32, 1, 1, 1}
+	peer2ipv6 := value.OID{2, 16, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 1}
+	tests := []struct {
+		name     string
+		oid      value.OID
+		index    value.OID
+		wantType pdu.VariableType
+		want     interface{}
+	}{
+		{"name", oidCbgpPeerAddrFamilyName, ipv4, pdu.VariableTypeOctetString, "ipv4"},
+		{"name of the ipv6 channel", oidCbgpPeerAddrFamilyName, ipv4v6, pdu.VariableTypeOctetString, "ipv6"},
+		{"accepted", oidCbgpPeerAcceptedPrefixes, ipv4, pdu.VariableTypeCounter32, uint32(10)},
+		{"denied", oidCbgpPeerDeniedPrefixes, ipv4, pdu.VariableTypeGauge32, uint32(2)},
+		{"admin limit", oidCbgpPeerPrefixAdminLimit, ipv4, pdu.VariableTypeGauge32, uint32(1000)},
+		{"no admin limit", oidCbgpPeerPrefixAdminLimit, ipv4v6, pdu.VariableTypeNoSuchObject, nil},
+		{"advertised", oidCbgpPeerAdvertisedPrefixes, ipv4, pdu.VariableTypeGauge32, uint32(5)},
+		{"ipv6 peer not in the ipv4 table", oidCbgpPeerAcceptedPrefixes, value.OID{0x20, 0x01, 0x0d, 0xb8, 2, 1}, pdu.VariableTypeNoSuchObject, nil},
+		{"peer2 name", oidCbgpPeer2AddrFamilyName, peer2ipv6, pdu.VariableTypeOctetString, "ipv6"},
+		{"peer2 ipv4 accepted", oidCbgpPeer2AcceptedPrefixes, peer2ipv4, pdu.VariableTypeCounter32, uint32(10)},
+		{"peer2 accepted", oidCbgpPeer2AcceptedPrefixes, peer2ipv6, pdu.VariableTypeCounter32, uint32(7)},
+		{"peer2 denied", oidCbgpPeer2DeniedPrefixes, peer2ipv6, pdu.VariableTypeGauge32, uint32(1)},
+		{"peer2 admin limit", oidCbgpPeer2PrefixAdminLimit, peer2ipv4, pdu.VariableTypeGauge32, uint32(1000)},
+		{"peer2 no admin limit", oidCbgpPeer2PrefixAdminLimit, peer2ipv6, pdu.VariableTypeNoSuchObject, nil},
+		{"peer2 advertised", oidCbgpPeer2AdvertisedPrefixes, peer2ipv6, pdu.VariableTypeGauge32, uint32(4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oid := append(append(value.OID{}, tt.oid...), tt.index...)
+			_, gotType, got, _ := data.Get(oid)
+			if gotType != tt.wantType || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%v) = %s %#v, want %s %#v", oid, gotType, got, tt.wantType, tt.want)
+			}
+		})
+	}
+}
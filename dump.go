@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// DumpCmd writes the tree of the registered subtrees after a single
+// refresh in a machine-readable format, e.g. as snmpsim data.
+type DumpCmd struct {
+	Format     string `short:"f" help:"output format: ${enum}" enum:"snmprec,json,csv" default:"snmprec"`
+	JuniperMib bool   `help:"also dump the BGP4-V2-MIB-JUNIPER compatibility subtree"`
+}
+
+func (c *DumpCmd) Run(ctx context.Context) error {
+	names, err := LoadBundledMibNames()
+	if err != nil {
+		return err
+	}
+	snapshot, err := refreshOnce(ctx)
+	if err != nil {
+		return err
+	}
+	subtrees := append([]value.OID{}, DefaultSubtrees...)
+	if c.JuniperMib {
+		subtrees = append(subtrees, oidJnxBgpM2)
+	}
+	entries, err := dumpSnapshot(snapshot, subtrees)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	switch c.Format {
+	case "snmprec":
+		writeSnmprec(w, entries)
+	case "json":
+		err = writeDumpJSON(w, entries, names)
+	case "csv":
+		err = writeDumpCSV(w, entries, names)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// dumpEntry is an object of the tree with its value in the text form of
+// snmprec files.
+type dumpEntry struct {
+	oid   value.OID
+	t     pdu.VariableType
+	hex   bool // value is hex encoded
+	value string
+}
+
+// tag is the snmprec type of e: the ASN.1 tag, with an x suffix for hex
+// encoded values.
+func (e dumpEntry) tag() string {
+	tag := strconv.Itoa(int(e.t))
+	if e.hex {
+		tag += "x"
+	}
+	return tag
+}
+
+// dumpTypeNames are the SMI names of the types served.
+var dumpTypeNames = map[pdu.VariableType]string{
+	pdu.VariableTypeInteger:          "Integer32",
+	pdu.VariableTypeOctetString:      "OctetString",
+	pdu.VariableTypeNull:             "Null",
+	pdu.VariableTypeObjectIdentifier: "ObjectIdentifier",
+	pdu.VariableTypeIPAddress:        "IpAddress",
+	pdu.VariableTypeCounter32:        "Counter32",
+	pdu.VariableTypeGauge32:          "Gauge32",
+	pdu.VariableTypeTimeTicks:        "TimeTicks",
+	pdu.VariableTypeOpaque:           "Opaque",
+	pdu.VariableTypeCounter64:        "Counter64",
+}
+
+// dumpSnapshot returns the objects of snapshot within subtrees in
+// lexicographic order, as snmpsim requires.
+func dumpSnapshot(snapshot *birdSnapshot, subtrees []value.OID) ([]dumpEntry, error) {
+	subtrees = append([]value.OID{}, subtrees...)
+	sort.Slice(subtrees, func(i int, j int) bool {
+		return compareOids(subtrees[i], subtrees[j]) == -1
+	})
+
+	var entries []dumpEntry
+	var err error
+	for _, subtree := range subtrees {
+		end := append(value.OID{}, subtree...)
+		end[len(end)-1]++
+		snapshot.data.Walk(subtree, true, end, func(oid value.OID, t pdu.VariableType, v interface{}) bool {
+			var entry dumpEntry
+			entry, err = newDumpEntry(oid, t, v)
+			entries = append(entries, entry)
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func newDumpEntry(oid value.OID, t pdu.VariableType, v interface{}) (dumpEntry, error) {
+	e := dumpEntry{oid: oid, t: t}
+	switch t {
+	case pdu.VariableTypeOctetString, pdu.VariableTypeOpaque:
+		var b []byte
+		switch v := v.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		default:
+			return e, fmt.Errorf("%v: unsupported %T value for %s", oid, v, t)
+		}
+		e.hex = t == pdu.VariableTypeOpaque || !snmprecPrintable(b)
+		e.value = string(b)
+		if e.hex {
+			e.value = hex.EncodeToString(b)
+		}
+	case pdu.VariableTypeIPAddress:
+		ip, ok := v.(net.IP)
+		if !ok {
+			return e, fmt.Errorf("%v: unsupported %T value for %s", oid, v, t)
+		}
+		e.value = ipv4OrZero(ip).String()
+	case pdu.VariableTypeTimeTicks:
+		d, ok := v.(time.Duration)
+		if !ok {
+			return e, fmt.Errorf("%v: unsupported %T value for %s", oid, v, t)
+		}
+		e.value = strconv.FormatUint(uint64(uint32(d/(10*time.Millisecond))), 10)
+	case pdu.VariableTypeNull:
+	case pdu.VariableTypeInteger, pdu.VariableTypeObjectIdentifier, pdu.VariableTypeCounter32, pdu.VariableTypeGauge32, pdu.VariableTypeCounter64:
+		e.value = fmt.Sprint(v)
+	default:
+		return e, fmt.Errorf("%v: unsupported type %s", oid, t)
+	}
+	return e, nil
+}
+
+// snmprecPrintable tells whether b can be written as is into a line of an
+// snmprec file.
+func snmprecPrintable(b []byte) bool {
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeSnmprec writes entries as lines of "OID|TAG|VALUE".
+func writeSnmprec(w io.Writer, entries []dumpEntry) {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s|%s|%s\n", e.oid, e.tag(), e.value)
+	}
+}
+
+type dumpJSONEntry struct {
+	OID   string      `json:"oid"`
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Tag   string      `json:"tag"`
+	Value interface{} `json:"value"`
+}
+
+// writeDumpJSON writes entries as an array of objects, with the values of
+// numeric types as numbers.
+func writeDumpJSON(w io.Writer, entries []dumpEntry, names *MibNames) error {
+	out := make([]dumpJSONEntry, len(entries))
+	for i, e := range entries {
+		out[i] = dumpJSONEntry{OID: e.oid.String(), Name: names.Name(e.oid), Type: dumpTypeNames[e.t], Tag: e.tag(), Value: e.value}
+		switch e.t {
+		case pdu.VariableTypeInteger, pdu.VariableTypeCounter32, pdu.VariableTypeGauge32, pdu.VariableTypeTimeTicks, pdu.VariableTypeCounter64:
+			out[i].Value = json.Number(e.value)
+		case pdu.VariableTypeNull:
+			out[i].Value = nil
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeDumpCSV writes entries with a header line.
+func writeDumpCSV(w io.Writer, entries []dumpEntry, names *MibNames) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"oid", "name", "type", "tag", "value"})
+	for _, e := range entries {
+		cw.Write([]string{e.oid.String(), names.Name(e.oid), dumpTypeNames[e.t], e.tag(), e.value})
+	}
+	cw.Flush()
+	return cw.Error()
+}
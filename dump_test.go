@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_newDumpEntry(t *testing.T) {
+	oid := value.OID{1, 3, 6, 1, 2, 1, 15, 1, 0}
+	tests := []struct {
+		t       pdu.VariableType
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{t: pdu.VariableTypeInteger, v: int32(-1), want: "1.3.6.1.2.1.15.1.0|2|-1"},
+		{t: pdu.VariableTypeOctetString, v: "ber1_gw1", want: "1.3.6.1.2.1.15.1.0|4|ber1_gw1"},
+		{t: pdu.VariableTypeOctetString, v: []byte{7, 232, 10}, want: "1.3.6.1.2.1.15.1.0|4x|07e80a"},
+		{t: pdu.VariableTypeOctetString, v: "two\nlines", want: "1.3.6.1.2.1.15.1.0|4x|74776f0a6c696e6573"},
+		{t: pdu.VariableTypeObjectIdentifier, v: "1.3.6.1.2.1.15", want: "1.3.6.1.2.1.15.1.0|6|1.3.6.1.2.1.15"},
+		{t: pdu.VariableTypeIPAddress, v: net.ParseIP("192.168.32.1"), want: "1.3.6.1.2.1.15.1.0|64|192.168.32.1"},
+		{t: pdu.VariableTypeIPAddress, v: net.ParseIP("2001:db8::1"), want: "1.3.6.1.2.1.15.1.0|64|0.0.0.0"},
+		{t: pdu.VariableTypeCounter32, v: uint32(12), want: "1.3.6.1.2.1.15.1.0|65|12"},
+		{t: pdu.VariableTypeGauge32, v: uint32(3600), want: "1.3.6.1.2.1.15.1.0|66|3600"},
+		{t: pdu.VariableTypeTimeTicks, v: 90*time.Second + 5*time.Millisecond, want: "1.3.6.1.2.1.15.1.0|67|9000"},
+		{t: pdu.VariableTypeCounter64, v: uint64(459), want: "1.3.6.1.2.1.15.1.0|70|459"},
+		{t: pdu.VariableTypeNull, want: "1.3.6.1.2.1.15.1.0|5|"},
+		{t: pdu.VariableTypeIPAddress, v: "192.168.32.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		e, err := newDumpEntry(oid, tt.t, tt.v)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newDumpEntry(%v, %v) error = %v, wantErr %v", tt.t, tt.v, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		var b strings.Builder
+		writeSnmprec(&b, []dumpEntry{e})
+		if got := strings.TrimSuffix(b.String(), "\n"); got != tt.want {
+			t.Errorf("newDumpEntry(%v, %v) = %s, want %s", tt.t, tt.v, got, tt.want)
+		}
+	}
+}
+
+func Test_dumpSnapshot(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	names, err := LoadBundledMibNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unsorted on purpose
+	entries, err := dumpSnapshot(h.current(), []value.OID{oidBird, oidBgp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("dumpSnapshot() returned nothing")
+	}
+	for i, e := range entries {
+		if !oidHasPrefix(e.oid, oidBgp) && !oidHasPrefix(e.oid, oidBird) {
+			t.Errorf("%v is outside of the subtrees", e.oid)
+		}
+		if i > 0 && compareOids(entries[i-1].oid, e.oid) != -1 {
+			t.Errorf("%v follows %v", e.oid, entries[i-1].oid)
+		}
+	}
+
+	var b strings.Builder
+	writeSnmprec(&b, entries)
+	if want := "1.3.6.1.2.1.15.1|4|4\n1.3.6.1.2.1.15.2.0|2|64846\n"; !strings.HasPrefix(b.String(), want) {
+		t.Errorf("writeSnmprec() = %q, want prefix %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := writeDumpJSON(&b, entries[1:2], names); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"oid": "1.3.6.1.2.1.15.2.0", "name": "bgpLocalAs.0", "type": "Integer32", "tag": "2", "value": float64(64846)}
+	if len(got) != 1 || len(got[0]) != len(want) {
+		t.Fatalf("writeDumpJSON() = %s", b.String())
+	}
+	for k, v := range want {
+		if got[0][k] != v {
+			t.Errorf("writeDumpJSON() %s = %v, want %v", k, got[0][k], v)
+		}
+	}
+
+	b.Reset()
+	if err := writeDumpCSV(&b, entries[:2], names); err != nil {
+		t.Fatal(err)
+	}
+	if want := "oid,name,type,tag,value\n1.3.6.1.2.1.15.1,bgpVersion,OctetString,4,4\n1.3.6.1.2.1.15.2.0,bgpLocalAs.0,Integer32,2,64846\n"; b.String() != want {
+		t.Errorf("writeDumpCSV() = %q, want %q", b.String(), want)
+	}
+}
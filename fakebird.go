@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A recording is the raw exchange with bird's control socket: the banner,
+// then every command as a line prefixed with birdRecordingCommand followed
+// by the reply lines with their codes. Lines starting with # are comments.
+const birdRecordingCommand = "> "
+
+// fakeBirdBanner greets clients of replies without a recorded banner.
+const fakeBirdBanner = "0001 BIRD fake-bird ready.\n"
+
+// BirdRecording holds raw replies of bird by command. The replies of a
+// command recorded several times are served in turn, the last one for
+// every further request.
+type BirdRecording struct {
+	banner  string
+	mu      sync.Mutex
+	replies map[string][]string
+	served  map[string]int
+}
+
+// birdReplyKey is the key of a command in a recording and the name of its
+// file in a directory of canned outputs, e.g. show_protocols_all_bgp1.
+func birdReplyKey(command string) string {
+	return strings.Join(strings.Fields(command), "_")
+}
+
+// LoadBirdRecording reads the recording file at path, or a directory of
+// canned outputs with a file named after every command. Canned outputs
+// are the raw reply or birdc's output without the reply codes.
+func LoadBirdRecording(path string) (*BirdRecording, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		recording, err := parseBirdRecording(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return recording, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	recording := newBirdRecording(fakeBirdBanner)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		recording.add(entry.Name(), cannedBirdReply(string(b)))
+	}
+	if len(recording.replies) == 0 {
+		return nil, fmt.Errorf("no canned outputs in %s", path)
+	}
+	return recording, nil
+}
+
+func newBirdRecording(banner string) *BirdRecording {
+	return &BirdRecording{banner: banner, replies: map[string][]string{}, served: map[string]int{}}
+}
+
+func (r *BirdRecording) add(command string, reply string) {
+	key := birdReplyKey(command)
+	r.replies[key] = append(r.replies[key], reply)
+}
+
+func parseBirdRecording(reader io.Reader) (*BirdRecording, error) {
+	recording := newBirdRecording("")
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	var command string
+	var reply strings.Builder
+	code := 0
+	flush := func() error {
+		if command == "" {
+			return nil
+		}
+		if !strings.HasSuffix(reply.String(), "\n") {
+			return fmt.Errorf("no reply to %q", command)
+		}
+		recording.add(command, reply.String())
+		reply.Reset()
+		return nil
+	}
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, birdRecordingCommand):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			command = strings.TrimPrefix(line, birdRecordingCommand)
+			code = 0
+			continue
+		}
+		parsed, err := parseBirdReplyLine(line, code)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		code = parsed.code
+		if command == "" {
+			if recording.banner != "" || parsed.code != birdCodeReady {
+				return nil, fmt.Errorf("line %d: reply line before a command", n)
+			}
+			recording.banner = line + "\n"
+			continue
+		}
+		reply.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(recording.replies) == 0 {
+		return nil, fmt.Errorf("no commands recorded")
+	}
+	if recording.banner == "" {
+		recording.banner = fakeBirdBanner
+	}
+	return recording, nil
+}
+
+// cannedBirdReply returns out as a raw reply. Output with reply codes is
+// served as is, birdc's output gets them added.
+func cannedBirdReply(out string) string {
+	out = strings.Trim(out, "\n")
+	lines := strings.Split(out, "\n")
+	if _, err := parseBirdReplyLine(lines[0], 0); err == nil && lines[0][0] != ' ' {
+		return out + "\n"
+	}
+	// birdc prints the banner first
+	if strings.HasPrefix(lines[0], "BIRD ") && strings.HasSuffix(lines[0], " ready.") {
+		lines = lines[1:]
+	}
+	var reply strings.Builder
+	for _, line := range lines {
+		reply.WriteString("1000-" + line + "\n")
+	}
+	reply.WriteString("0000 \n")
+	return reply.String()
+}
+
+// reply returns the next reply to command.
+func (r *BirdRecording) reply(command string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := birdReplyKey(command)
+	replies := r.replies[key]
+	if len(replies) == 0 {
+		return "", false
+	}
+	i := min(r.served[key], len(replies)-1)
+	r.served[key]++
+	return replies[i], true
+}
+
+// FakeBird serves a recording on a unix socket speaking the bird cli
+// protocol, a stand-in for bird to run the agent against.
+type FakeBird struct {
+	listener  net.Listener
+	recording *BirdRecording
+}
+
+// NewFakeBird listens on the unix socket at path. A stale socket is
+// replaced, one bird is listening on is not.
+func NewFakeBird(path string, recording *BirdRecording) (*FakeBird, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &FakeBird{listener: listener, recording: recording}, nil
+}
+
+// Serve answers connections until ctx is done.
+func (f *FakeBird) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		f.listener.Close()
+	})
+	defer stop()
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f.serve(conn)
+	}
+}
+
+// Close stops listening, connections already accepted are served until
+// their clients close them.
+func (f *FakeBird) Close() error {
+	return f.listener.Close()
+}
+
+func (f *FakeBird) serve(conn net.Conn) {
+	defer conn.Close()
+	if _, err := io.WriteString(conn, f.recording.banner); err != nil {
+		return
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		reply, ok := f.recording.reply(command)
+		switch {
+		case ok:
+		case strings.HasPrefix(command, "echo "):
+			// no log messages follow
+			reply = "0000 \n"
+		default:
+			log.Printf("[WARN] No reply to %q recorded", command)
+			reply = "9001 Command not recorded\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// FakeBirdCmd serves a recording on --bird-sock in place of bird.
+type FakeBirdCmd struct {
+	Recording string `arg:"" help:"recording file or directory of canned outputs named after their commands, e.g. show_protocols_all" type:"path"`
+}
+
+func (c *FakeBirdCmd) Run(ctx context.Context) error {
+	recording, err := LoadBirdRecording(c.Recording)
+	if err != nil {
+		return err
+	}
+	f, err := NewFakeBird(CLI.BirdSock, recording)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Serving %s on %s", c.Recording, CLI.BirdSock)
+	return f.Serve(ctx)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBirdRecording(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+
+	commands := []string{"show status", "show protocols", "show protocols all nonexistent"}
+	var transcript strings.Builder
+	c, err := DialBirdRecording(context.Background(), path, &transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{}
+	for _, command := range commands {
+		out, _ := c.Command(context.Background(), command)
+		want[command] = out
+	}
+	c.Close()
+
+	if !strings.HasPrefix(transcript.String(), "0001 BIRD 2.15.1 ready.\n> show status\n1000-BIRD 2.15.1\n") {
+		t.Errorf("transcript = %q", transcript.String())
+	}
+	recording, err := parseBirdRecording(strings.NewReader("# comment\n" + transcript.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := filepath.Join(dir, "replay.ctl")
+	f, err := NewFakeBird(replay, recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go f.Serve(context.Background())
+	defer f.Close()
+
+	if _, err := NewFakeBird(replay, recording); err == nil {
+		t.Error("NewFakeBird() succeeded on a socket in use")
+	}
+
+	c, err = DialBird(context.Background(), replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, command := range commands {
+		got, err := c.Command(context.Background(), command)
+		if got != want[command] {
+			t.Errorf("Command(%q) = %q, %v, want %q", command, got, err, want[command])
+		}
+	}
+	var replyErr *BirdReplyError
+	if _, err := c.Command(context.Background(), "show route"); !errors.As(err, &replyErr) || replyErr.Code != 9001 {
+		t.Errorf("Command() of an unrecorded command error = %v, want 9001", err)
+	}
+}
+
+func Test_parseBirdRecording(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "replies in turn", in: "0001 BIRD 2.15.1 ready.\n> show status\n0013 first\n> show status\n0013 second\n"},
+		{name: "no banner", in: "> show status\n0013 Daemon is up and running\n"},
+		{name: "malformed line", in: "0001 BIRD 2.15.1 ready.\n> show status\nDaemon is up and running\n", wantErr: true},
+		{name: "no reply", in: "0001 BIRD 2.15.1 ready.\n> show status\n> show protocols\n0000 \n", wantErr: true},
+		{name: "reply before a command", in: "0013 Daemon is up and running\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBirdRecording(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseBirdRecording() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	recording, err := parseBirdRecording(strings.NewReader(tests[0].in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"0013 first\n", "0013 second\n", "0013 second\n"} {
+		if got, _ := recording.reply("show  status"); got != want {
+			t.Errorf("reply() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFakeBirdCannedOutputs(t *testing.T) {
+	dir := t.TempDir()
+	canned := filepath.Join(dir, "canned")
+	if err := os.Mkdir(canned, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{
+		"show_status":        StatusInDefault,
+		"show_protocols_all": showProtocolsAllDefault,
+	} {
+		if err := os.WriteFile(filepath.Join(canned, name), []byte(out), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recording, err := LoadBirdRecording(canned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bird.ctl")
+	f, err := NewFakeBird(path, recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go f.Serve(context.Background())
+	defer f.Close()
+
+	h := &BirdBGPHandler{conn: NewBirdConn(path, time.Second), started: time.Now(), StalePolicy: StalePolicyFlag}
+	defer h.conn.Close()
+	if err := h.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := h.current()
+	if snapshot.status.RouterId.String() != "192.168.32.79" {
+		t.Errorf("RouterId = %v, want 192.168.32.79", snapshot.status.RouterId)
+	}
+	if len(snapshot.protocols) != 2 || snapshot.protocols[0].Name != "ber1_gw1" {
+		t.Errorf("protocols = %+v, want ber1_gw1 and xxx_gw1", snapshot.protocols)
+	}
+}
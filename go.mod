@@ -2,8 +2,8 @@ module krglv.com/bird2snmp
 
 go 1.21
 
-require github.com/natesales/go-bird v0.0.0-20240913170738-8c0ae839b7f1
-
 require github.com/posteo/go-agentx v0.2.1
 
 require github.com/alecthomas/kong v1.2.1
+
+replace github.com/posteo/go-agentx => ./third_party/go-agentx
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPServer serves the data of a BirdBGPHandler as Prometheus metrics and
+// through a JSON API. Responses are rendered from the snapshot served over
+// SNMP, requests never query bird.
+type HTTPServer struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewHTTPServer listens on the TCP address addr for requests of /metrics and
+// /api/v1/ about the data of handler.
+func NewHTTPServer(addr string, handler *BirdBGPHandler) (*HTTPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler{handler})
+	mux.Handle("/api/v1/", apiHandler{handler})
+	return &HTTPServer{
+		listener: listener,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *HTTPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve answers requests until ctx is done.
+func (s *HTTPServer) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(shutdownCtx)
+	}()
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// BGP4-V2-MIB-JUNIPER peer and prefix counter tables, served for collectors
+// that only know Juniper routers.
+var (
+	oidJnxBgpM2                         = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1}
+	oidJnxBgpM2PeerIdentifier           = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 1}
+	oidJnxBgpM2PeerState                = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 2}
+	oidJnxBgpM2PeerStatus               = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 3}
+	oidJnxBgpM2PeerConfiguredVersion    = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 4}
+	oidJnxBgpM2PeerNegotiatedVersion    = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 5}
+	oidJnxBgpM2PeerLocalAddrType        = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 6}
+	oidJnxBgpM2PeerLocalAddr            = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 7}
+	oidJnxBgpM2PeerLocalPort            = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 8}
+	oidJnxBgpM2PeerLocalAs              = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 9}
+	oidJnxBgpM2PeerRemoteAddrType       = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 10}
+	oidJnxBgpM2PeerRemoteAddr           = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 11}
+	oidJnxBgpM2PeerRemotePort           = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 12}
+	oidJnxBgpM2PeerRemoteAs             = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 13}
+	oidJnxBgpM2PeerIndex                = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 14}
+	oidJnxBgpM2PeerRoutingInstance      = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 1, 1, 1, 15}
+	oidJnxBgpM2PrefixInPrefixes         = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 7}
+	oidJnxBgpM2PrefixInPrefixesAccepted = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 8}
+	oidJnxBgpM2PrefixInPrefixesRejected = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 9}
+	oidJnxBgpM2PrefixOutPrefixes        = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 10}
+	oidJnxBgpM2PrefixInPrefixesActive   = value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 11}
+)
+
+// jnxBgpM2RoutingInstance is the jnxBgpM2PeerRoutingInstance of every peer,
+// the default routing instance of junos.
+const jnxBgpM2RoutingInstance = 0
+
+// peerIndexes hands out jnxBgpM2PeerIndex values. A protocol keeps its
+// index for the lifetime of the agent and indexes are never reused, so
+// collectors see stable rows across refreshes.
+type peerIndexes struct {
+	last   uint32
+	byName map[string]uint32
+}
+
+func (p *peerIndexes) get(name string) uint32 {
+	if p.byName == nil {
+		p.byName = make(map[string]uint32)
+	}
+	if index, ok := p.byName[name]; ok {
+		return index
+	}
+	p.last++
+	p.byName[name] = p.last
+	return p.last
+}
+
+// jnxBgpM2Peer is a row of jnxBgpM2PeerTable.
+type jnxBgpM2Peer struct {
+	index uint32
+	proto ProtocolBGPStatus
+}
+
+// jnxBgpM2PeerColumns are the served jnxBgpM2PeerEntry columns in OID order.
+var jnxBgpM2PeerColumns = []tableColumn[jnxBgpM2Peer]{
+	{oidJnxBgpM2PeerIdentifier, pdu.VariableTypeOctetString, func(row jnxBgpM2Peer) interface{} {
+		return string(ipv4OrZero(row.proto.NeighborId))
+	}},
+	{oidJnxBgpM2PeerState, pdu.VariableTypeInteger, func(row jnxBgpM2Peer) interface{} {
+		return bgpState(row.proto.State)
+	}},
+	{oidJnxBgpM2PeerStatus, pdu.VariableTypeInteger, func(row jnxBgpM2Peer) interface{} {
+		// halted(1) for protocols disabled in bird, running(2) otherwise
+		if row.proto.ProtoState == "down" {
+			return int32(1)
+		}
+		return int32(2)
+	}},
+	{oidJnxBgpM2PeerConfiguredVersion, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return uint32(4)
+	}},
+	{oidJnxBgpM2PeerNegotiatedVersion, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		if row.proto.State == "Established" {
+			return uint32(4)
+		}
+		return uint32(0)
+	}},
+	{oidJnxBgpM2PeerLocalAddrType, pdu.VariableTypeInteger, func(row jnxBgpM2Peer) interface{} {
+		addrType, _ := inetAddress(row.proto.SourceAddress, row.proto.NeighborInterface)
+		return addrType
+	}},
+	{oidJnxBgpM2PeerLocalAddr, pdu.VariableTypeOctetString, func(row jnxBgpM2Peer) interface{} {
+		_, addr := inetAddress(row.proto.SourceAddress, row.proto.NeighborInterface)
+		return string(addr)
+	}},
+	{oidJnxBgpM2PeerLocalPort, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		// bird does not report the local port of the session
+		return uint32(0)
+	}},
+	{oidJnxBgpM2PeerLocalAs, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return row.proto.LocalAs
+	}},
+	{oidJnxBgpM2PeerRemoteAddrType, pdu.VariableTypeInteger, func(row jnxBgpM2Peer) interface{} {
+		addrType, _ := inetAddress(row.proto.NeighborAddress, row.proto.NeighborInterface)
+		return addrType
+	}},
+	{oidJnxBgpM2PeerRemoteAddr, pdu.VariableTypeOctetString, func(row jnxBgpM2Peer) interface{} {
+		_, addr := inetAddress(row.proto.NeighborAddress, row.proto.NeighborInterface)
+		return string(addr)
+	}},
+	{oidJnxBgpM2PeerRemotePort, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return uint32(remotePort(row.proto))
+	}},
+	{oidJnxBgpM2PeerRemoteAs, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return row.proto.NeighborAs
+	}},
+	{oidJnxBgpM2PeerIndex, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return row.index
+	}},
+	{oidJnxBgpM2PeerRoutingInstance, pdu.VariableTypeGauge32, func(row jnxBgpM2Peer) interface{} {
+		return uint32(jnxBgpM2RoutingInstance)
+	}},
+}
+
+// jnxBgpM2PrefixCountersColumns are the served jnxBgpM2PrefixCountersEntry
+// columns in OID order, the counters follow the AFI (1) and SAFI (2) index
+// columns from column 7 on.
+var jnxBgpM2PrefixCountersColumns = []tableColumn[ProtocolBGPChannel]{
+	{oidJnxBgpM2PrefixInPrefixes, pdu.VariableTypeGauge32, func(channel ProtocolBGPChannel) interface{} {
+		return uint32(channel.Imported + channel.Filtered)
+	}},
+	{oidJnxBgpM2PrefixInPrefixesAccepted, pdu.VariableTypeGauge32, func(channel ProtocolBGPChannel) interface{} {
+		return uint32(channel.Imported)
+	}},
+	{oidJnxBgpM2PrefixInPrefixesRejected, pdu.VariableTypeGauge32, func(channel ProtocolBGPChannel) interface{} {
+		return uint32(channel.Filtered)
+	}},
+	{oidJnxBgpM2PrefixOutPrefixes, pdu.VariableTypeGauge32, func(channel ProtocolBGPChannel) interface{} {
+		return uint32(channel.Exported)
+	}},
+	{oidJnxBgpM2PrefixInPrefixesActive, pdu.VariableTypeGauge32, func(channel ProtocolBGPChannel) interface{} {
+		return uint32(channel.Preferred)
+	}},
+}
+
+// jnxBgpM2Peers returns peers indexed by routing instance, local address
+// and remote address, and their channels indexed by jnxBgpM2PeerIndex, AFI
+// and SAFI.
+func jnxBgpM2Peers(indexes *peerIndexes, protocols []ProtocolBGPStatus) ([]tableRow[jnxBgpM2Peer], []tableRow[ProtocolBGPChannel]) {
+	peers := []tableRow[jnxBgpM2Peer]{}
+	channels := []tableRow[ProtocolBGPChannel]{}
+	for _, proto := range protocols {
+		remoteType, remoteAddr := inetAddress(proto.NeighborAddress, proto.NeighborInterface)
+		if remoteAddr == nil {
+			continue
+		}
+		localType, localAddr := inetAddress(proto.SourceAddress, proto.NeighborInterface)
+		index := value.OID{jnxBgpM2RoutingInstance}
+		index = append(index, inetAddressToOid(localType, localAddr)...)
+		index = append(index, inetAddressToOid(remoteType, remoteAddr)...)
+		peer := jnxBgpM2Peer{index: indexes.get(proto.Name), proto: proto}
+		peers = append(peers, tableRow[jnxBgpM2Peer]{index: index, row: peer})
+
+		for _, channel := range proto.Channels {
+			if channel.Afi == 0 {
+				continue
+			}
+			index := value.OID{peer.index, uint32(channel.Afi), uint32(channel.Safi)}
+			channels = append(channels, tableRow[ProtocolBGPChannel]{index: index, row: channel})
+		}
+	}
+	return peers, channels
+}
+
+func addJnxBgpM2Tables(data *ListHandler, indexes *peerIndexes, protocols []ProtocolBGPStatus) {
+	peers, channels := jnxBgpM2Peers(indexes, protocols)
+	addTable(data, jnxBgpM2PeerColumns, peers)
+	addTable(data, jnxBgpM2PrefixCountersColumns, channels)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_peerIndexes(t *testing.T) {
+	indexes := peerIndexes{}
+	for _, tt := range []struct {
+		name string
+		want uint32
+	}{
+		{"peer1", 1},
+		{"peer2", 2},
+		{"peer1", 1},
+		{"peer3", 3},
+		{"peer2", 2},
+	} {
+		if got := indexes.get(tt.name); got != tt.want {
+			t.Errorf("peerIndexes.get(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_addJnxBgpM2Tables(t *testing.T) {
+	protocols := []ProtocolBGPStatus{{
+		Name:            "gw1",
+		ProtoState:      "up",
+		State:           "Established",
+		NeighborAddress: net.ParseIP("192.168.32.1"),
+		NeighborAs:      64512,
+		NeighborId:      net.ParseIP("10.0.0.1"),
+		LocalAs:         64496,
+		SourceAddress:   net.ParseIP("192.168.32.2"),
+		Channels: map[string]ProtocolBGPChannel{
+			"ipv4": {Name: "ipv4", Afi: 1, Safi: 1, Imported: 10, Filtered: 2, Exported: 5, Preferred: 8},
+		},
+	}, {
+		Name:            "gw2",
+		ProtoState:      "down",
+		State:           "Idle",
+		NeighborAddress: net.ParseIP("2001:db8::1"),
+		Channels: map[string]ProtocolBGPChannel{
+			"ipv6": {Name: "ipv6", Afi: 2, Safi: 1, Imported: 3},
+		},
+	}}
+	data := &ListHandler{}
+	addJnxBgpM2Tables(data, &peerIndexes{}, protocols)
+
+	// routing instance, local and remote InetAddressType, length and address
+	peer1 := value.OID{0, 1, 4, 192, 168, 32, 2, 1, 4, 192, 168, 32, 1}
+	peer2 := value.OID{0, 0, 0, 2, 16, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	// jnxBgpM2PeerIndex, AFI and SAFI
+	ipv4 := value.OID{1, 1, 1}
+	ipv6 := value.OID{2, 2, 1}
+	tests := []struct {
+		name     string
+		oid      value.OID
+		index    value.OID
+		wantType pdu.VariableType
+		want     interface{}
+	}{
+		{"peer identifier", oidJnxBgpM2PeerIdentifier, peer1, pdu.VariableTypeOctetString, string([]byte{10, 0, 0, 1})},
+		{"peer state", oidJnxBgpM2PeerState, peer1, pdu.VariableTypeInteger, int32(6)},
+		{"peer status running", oidJnxBgpM2PeerStatus, peer1, pdu.VariableTypeInteger, int32(2)},
+		{"peer status halted", oidJnxBgpM2PeerStatus, peer2, pdu.VariableTypeInteger, int32(1)},
+		{"negotiated version", oidJnxBgpM2PeerNegotiatedVersion, peer1, pdu.VariableTypeGauge32, uint32(4)},
+		{"local addr type", oidJnxBgpM2PeerLocalAddrType, peer1, pdu.VariableTypeInteger, int32(1)},
+		{"local addr", oidJnxBgpM2PeerLocalAddr, peer1, pdu.VariableTypeOctetString, string([]byte{192, 168, 32, 2})},
+		{"local as", oidJnxBgpM2PeerLocalAs, peer1, pdu.VariableTypeGauge32, uint32(64496)},
+		{"remote addr type", oidJnxBgpM2PeerRemoteAddrType, peer2, pdu.VariableTypeInteger, int32(2)},
+		{"remote as", oidJnxBgpM2PeerRemoteAs, peer1, pdu.VariableTypeGauge32, uint32(64512)},
+		{"peer index", oidJnxBgpM2PeerIndex, peer2, pdu.VariableTypeGauge32, uint32(2)},
+		{"routing instance", oidJnxBgpM2PeerRoutingInstance, peer1, pdu.VariableTypeGauge32, uint32(0)},
+		{"in prefixes", oidJnxBgpM2PrefixInPrefixes, ipv4, pdu.VariableTypeGauge32, uint32(12)},
+		{"in prefixes accepted", oidJnxBgpM2PrefixInPrefixesAccepted, ipv4, pdu.VariableTypeGauge32, uint32(10)},
+		{"in prefixes accepted ipv6", oidJnxBgpM2PrefixInPrefixesAccepted, ipv6, pdu.VariableTypeGauge32, uint32(3)},
+		{"in prefixes rejected", oidJnxBgpM2PrefixInPrefixesRejected, ipv4, pdu.VariableTypeGauge32, uint32(2)},
+		{"out prefixes", oidJnxBgpM2PrefixOutPrefixes, ipv4, pdu.VariableTypeGauge32, uint32(5)},
+		{"in prefixes active", oidJnxBgpM2PrefixInPrefixesActive, ipv4, pdu.VariableTypeGauge32, uint32(8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oid := append(append(value.OID{}, tt.oid...), tt.index...)
+			_, gotType, got, _ := data.Get(oid)
+			if gotType != tt.wantType || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%v) = %s %#v, want %s %#v", oid, gotType, got, tt.wantType, tt.want)
+			}
+		})
+	}
+
+	// the column numbers collectors poll, jnxBgpM2PrefixInPrefixesAccepted
+	// is column 8 of jnxBgpM2PrefixCountersEntry
+	accepted := value.OID{1, 3, 6, 1, 4, 1, 2636, 5, 1, 1, 2, 6, 2, 1, 8, 1, 1, 1}
+	if _, _, got, _ := data.Get(accepted); got != uint32(10) {
+		t.Errorf("Get(%v) = %#v, want jnxBgpM2PrefixInPrefixesAccepted 10", accepted, got)
+	}
+}
@@ -1,61 +1,132 @@
 package main
 
 import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
 	"github.com/posteo/go-agentx"
 	"github.com/posteo/go-agentx/pdu"
 	"github.com/posteo/go-agentx/value"
 )
 
+// listEntry is an oid with its list item.
+type listEntry struct {
+	oid  value.OID
+	item *agentx.ListItem
+}
+
 // ListHandler is a helper that takes a list of oids and implements
-// a default behaviour for that list.
+// a default behaviour for that list. The oids are kept sorted
+// lexicographically whatever the order they are added in, lookups are
+// binary searches.
 type ListHandler struct {
-	oids  []value.OID
-	items map[string]*agentx.ListItem
+	mu       sync.Mutex
+	entries  []listEntry
+	unsorted atomic.Bool // entries were added out of order since the last sort
 }
 
-// Add adds a list item for the provided oid and returns it.
+// Add adds a list item for the provided oid and returns it. An item already
+// added for oid is replaced. Items are appended, those added out of order
+// are sorted once by Sort or the next lookup. Add must not be called
+// concurrently with lookups.
 func (l *ListHandler) Add(oid value.OID) *agentx.ListItem {
-	if l.items == nil {
-		l.items = make(map[string]*agentx.ListItem)
+	entry := listEntry{oid: append(value.OID{}, oid...), item: &agentx.ListItem{}}
+
+	n := len(l.entries)
+	if n > 0 && !l.unsorted.Load() {
+		switch compareOids(l.entries[n-1].oid, oid) {
+		case 0:
+			l.entries[n-1] = entry
+			return entry.item
+		case 1:
+			l.unsorted.Store(true)
+		}
+	}
+	l.entries = append(l.entries, entry)
+	return entry.item
+}
+
+// Sort sorts the items added out of order, the last item added for an oid
+// replacing the previous ones. Lookups sort on their own, sorting before
+// serving saves the first request the work.
+func (l *ListHandler) Sort() {
+	if !l.unsorted.Load() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.unsorted.Load() {
+		return
+	}
+	sort.SliceStable(l.entries, func(i int, j int) bool {
+		return compareOids(l.entries[i].oid, l.entries[j].oid) == -1
+	})
+	entries := l.entries[:0]
+	for i, entry := range l.entries {
+		if i+1 < len(l.entries) && compareOids(entry.oid, l.entries[i+1].oid) == 0 {
+			continue
+		}
+		entries = append(entries, entry)
 	}
+	l.entries = entries
+	l.unsorted.Store(false)
+}
 
-	l.oids = append(l.oids, oid)
-	item := &agentx.ListItem{}
-	l.items[oid.String()] = item
-	return item
+// search returns the index of the first entry not less than oid.
+func (l *ListHandler) search(oid value.OID) int {
+	l.Sort()
+	return sort.Search(len(l.entries), func(i int) bool {
+		return compareOids(l.entries[i].oid, oid) != -1
+	})
 }
 
 // Get tries to find the provided oid and returns the corresponding value.
 func (l *ListHandler) Get(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
-	if l.items == nil {
+	i := l.search(oid)
+	if i == len(l.entries) || compareOids(l.entries[i].oid, oid) != 0 {
 		return nil, pdu.VariableTypeNoSuchObject, nil, nil
 	}
-
-	item, ok := l.items[oid.String()]
-	if ok {
-		return oid, item.Type, item.Value, nil
-	}
-	return nil, pdu.VariableTypeNoSuchObject, nil, nil
+	return l.entries[i].value()
 }
 
 // GetNext tries to find the value that follows the provided oid and returns it.
 func (l *ListHandler) GetNext(from value.OID, includeFrom bool, to value.OID) (value.OID, pdu.VariableType, interface{}, error) {
-	if l.items == nil {
+	i := l.first(from, includeFrom)
+	if i == len(l.entries) || compareOids(l.entries[i].oid, to) != -1 {
 		return nil, pdu.VariableTypeNoSuchObject, nil, nil
 	}
+	return l.entries[i].value()
+}
 
-	for _, oid := range l.oids {
-		if oidWithin(oid, from, includeFrom, to) {
-			return l.Get(oid)
+// Walk calls fn for every oid following from up to, but excluding, to in
+// lexicographic order until fn returns false.
+func (l *ListHandler) Walk(from value.OID, includeFrom bool, to value.OID, fn func(oid value.OID, t pdu.VariableType, v interface{}) bool) {
+	for i := l.first(from, includeFrom); i < len(l.entries); i++ {
+		if compareOids(l.entries[i].oid, to) != -1 {
+			return
+		}
+		oid, t, v, _ := l.entries[i].value()
+		if !fn(oid, t, v) {
+			return
 		}
 	}
-
-	return nil, pdu.VariableTypeNoSuchObject, nil, nil
 }
 
-func oidWithin(oid value.OID, from value.OID, includeFrom bool, to value.OID) bool {
-	fromCompare := compareOids(from, oid)
-	toCompare := compareOids(to, oid)
+// first returns the index of the first entry following from, or of from
+// itself if includeFrom is set.
+func (l *ListHandler) first(from value.OID, includeFrom bool) int {
+	i := l.search(from)
+	if !includeFrom && i < len(l.entries) && compareOids(l.entries[i].oid, from) == 0 {
+		i++
+	}
+	return i
+}
 
-	return (fromCompare == -1 || (fromCompare == 0 && includeFrom)) && (toCompare == 1)
+func (e listEntry) value() (value.OID, pdu.VariableType, interface{}, error) {
+	// values changing between refreshes are computed on request
+	if value, ok := e.item.Value.(func() interface{}); ok {
+		return e.oid, e.item.Type, value(), nil
+	}
+	return e.oid, e.item.Type, e.item.Value, nil
 }
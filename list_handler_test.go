@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func TestListHandler(t *testing.T) {
+	l := &ListHandler{}
+	// added out of order and with a duplicate
+	for _, oid := range []value.OID{{1, 3, 2}, {1, 3, 10}, {1, 3, 1, 5}, {1, 3, 1}, {1, 4}, {1, 3, 2}} {
+		item := l.Add(oid)
+		item.Type = pdu.VariableTypeInteger
+		item.Value = int32(oid[len(oid)-1])
+	}
+
+	var walked []string
+	l.Walk(value.OID{1}, false, value.OID{2}, func(oid value.OID, t pdu.VariableType, v interface{}) bool {
+		walked = append(walked, oid.String())
+		return true
+	})
+	want := []string{"1.3.1", "1.3.1.5", "1.3.2", "1.3.10", "1.4"}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk() = %v, want %v", walked, want)
+	}
+
+	tests := []struct {
+		name        string
+		from        value.OID
+		includeFrom bool
+		to          value.OID
+		want        value.OID
+	}{
+		{name: "before the first", from: value.OID{1, 3}, to: value.OID{2}, want: value.OID{1, 3, 1}},
+		{name: "include from", from: value.OID{1, 3, 1}, includeFrom: true, to: value.OID{2}, want: value.OID{1, 3, 1}},
+		{name: "exclude from", from: value.OID{1, 3, 1}, to: value.OID{2}, want: value.OID{1, 3, 1, 5}},
+		{name: "between", from: value.OID{1, 3, 3}, to: value.OID{2}, want: value.OID{1, 3, 10}},
+		{name: "to is exclusive", from: value.OID{1, 3, 10}, to: value.OID{1, 4}},
+		{name: "after the last", from: value.OID{1, 4}, to: value.OID{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := l.GetNext(tt.from, tt.includeFrom, tt.to)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNext() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, _, v, _ := l.Get(value.OID{1, 3, 10}); v != int32(10) {
+		t.Errorf("Get() = %v, want 10", v)
+	}
+	if got, gotType, _, _ := l.Get(value.OID{1, 3, 3}); got != nil || gotType != pdu.VariableTypeNoSuchObject {
+		t.Errorf("Get() = %v %v, want noSuchObject", got, gotType)
+	}
+}
+
+func TestListHandlerConcurrentSort(t *testing.T) {
+	l := &ListHandler{}
+	for i := 100; i > 0; i-- {
+		l.Add(value.OID{1, 3, uint32(i)}).Type = pdu.VariableTypeNull
+	}
+	// the first lookups sort, concurrently
+	done := make(chan value.OID)
+	for i := 0; i < 4; i++ {
+		go func() {
+			oid, _, _, _ := l.GetNext(value.OID{1, 3}, false, value.OID{2})
+			done <- oid
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if oid := <-done; !reflect.DeepEqual(oid, value.OID{1, 3, 1}) {
+			t.Errorf("GetNext() = %v, want 1.3.1", oid)
+		}
+	}
+}
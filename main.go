@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -9,55 +12,207 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/value"
 )
 
 var CLI struct {
-	BirdSock            string        `short:"s" help:"bird socket path" default:"/run/bird/bird.ctl"`
+	BirdSock    string        `short:"s" help:"bird socket path" default:"/run/bird/bird.ctl"`
+	BirdTimeout time.Duration `help:"bird command timeout" default:"10s"`
+
+	Run   RunCmd   `cmd:"" default:"withargs" help:"serve bird's data over SNMP (default)"`
+	Walk  WalkCmd  `cmd:"" help:"refresh once and print the served OID tree"`
+	Check CheckCmd `cmd:"" help:"check the BGP sessions as a Nagios plugin"`
+	Dump  DumpCmd  `cmd:"" help:"refresh once and write the served OID tree as snmprec, JSON or CSV"`
+
+	Record   RecordCmd   `cmd:"" help:"record bird's replies to the commands of a refresh"`
+	FakeBird FakeBirdCmd `cmd:"" name:"fake-bird" help:"serve a recording on the bird socket path in place of bird"`
+}
+
+// RunCmd serves bird's data through an agentx master or a standalone agent
+// until it is signalled.
+type RunCmd struct {
 	BirdRefreshInterval time.Duration `short:"r" help:"bird data refresh interval" default:"3s"`
+	BirdEcho            bool          `help:"follow bird's log to refresh changed protocols right away" default:"true" negatable:""`
+	BirdResyncInterval  time.Duration `help:"full refresh interval once bird logs protocol state changes or while refreshing incrementally" default:"30s"`
+	BirdIncremental     bool          `help:"poll the show protocols summary and fetch changed protocols only, full refresh every --bird-resync-interval"`
+	BirdRecord          string        `help:"record every exchange with bird to this file, replayable with fake-bird" type:"path"`
+	MaxDataAge          time.Duration `help:"age after which bird data is stale, 0 disables" default:"1m"`
+	StalePolicy         string        `help:"how to serve stale data: ${enum}" enum:"flag,nosuchinstance,generr" default:"flag"`
 	SnmpMasterSock      string        `short:"x" help:"snmpd agentx master socket path" default:"/var/agentx/master"`
+	SnmpListen          string        `help:"answer SNMPv1/v2c requests on this UDP address instead of using an agentx master, e.g. :161"`
+	SnmpCommunity       string        `help:"community of SNMPv1/v2c requests with --snmp-listen, empty to answer SNMPv3 only" default:"public"`
+	SnmpUsers           string        `help:"JSON file with SNMPv3 users and views for --snmp-listen" type:"existingfile"`
+	SnmpEngineFile      string        `help:"file keeping the SNMPv3 engine ID and boots" default:"/var/lib/bird2snmp/snmp-engine.json"`
 	SnmpPriority        byte          `short:"p" help:"snmpd registration priority" default:"127"`
+	SnmpNotifications   bool          `help:"send BGP4-MIB notifications on peer state changes" default:"true" negatable:""`
+	HttpListen          string        `help:"serve Prometheus metrics on /metrics and the JSON API on /api/v1/ of this TCP address, e.g. :9324"`
+	JuniperMib          bool          `help:"also register the BGP4-V2-MIB-JUNIPER compatibility subtree"`
 }
 
 func main() {
-	kong.Parse(&CLI)
+	parser := kong.Must(&CLI)
+	cli, err := parser.Parse(os.Args[1:])
+	if isCheckParseError(err) {
+		fmt.Printf("BIRD %s - %v\n", checkStateNames[checkUnknown], err)
+		os.Exit(checkUnknown)
+	}
+	parser.FatalIfErrorf(err)
+
+	// Set up signal handling for graceful shutdown, a pending refresh is
+	// cancelled as well
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	cli.BindTo(ctx, (*context.Context)(nil))
+	err = cli.Run()
+	var status checkStatus
+	if errors.As(err, &status) {
+		os.Exit(int(status))
+	}
+	cli.FatalIfErrorf(err)
+}
 
+func (c *RunCmd) Run(ctx context.Context) error {
 	zone, offset := time.Now().Zone()
 	log.Printf("[DEBUG] local timezone is %s (%+.02fh)", zone, float32(offset)/60/60)
 
-	snmpclient, err := agentx.Dial("unix", CLI.SnmpMasterSock)
-	if err != nil {
-		log.Fatalf("Error connecting to SNMP master: %v", err)
+	var recorder *birdRecorder
+	if c.BirdRecord != "" {
+		f, err := os.Create(c.BirdRecord)
+		if err != nil {
+			return fmt.Errorf("failed to create bird recording: %w", err)
+		}
+		defer f.Close()
+		recorder = newBirdRecorder(f, CLI.BirdSock)
+		log.Printf("[INFO] Recording the exchanges with bird to %s", c.BirdRecord)
+	}
+
+	var conn *BirdConn
+	if recorder != nil {
+		conn = NewBirdConnRecording(CLI.BirdSock, CLI.BirdTimeout, recorder.transcript(false))
+	} else {
+		conn = NewBirdConn(CLI.BirdSock, CLI.BirdTimeout)
 	}
-	snmpclient.Timeout = 1 * time.Minute
-	snmpclient.ReconnectInterval = 1 * time.Second
+	defer conn.Close()
+	handler := NewBirdBGPHandler(ctx, conn)
+	handler.MaxDataAge = c.MaxDataAge
+	handler.StalePolicy = c.StalePolicy
 
-	handler, err := NewBirdBGPHandler(CLI.BirdSock)
-	if err != nil {
-		log.Fatalf("Error initializing BGP handler: %v", err)
+	subtrees := append([]value.OID{}, DefaultSubtrees...)
+	if c.JuniperMib {
+		subtrees = append(subtrees, oidJnxBgpM2)
 	}
 
-	if err := handler.Register(CLI.SnmpPriority, snmpclient); err != nil {
-		log.Fatalf("Error registering SNMP handler: %v", err)
+	// errors of the servers end the agent, returned for the deferred
+	// cleanup to run
+	errs := make(chan error, 2)
+	if c.SnmpListen != "" {
+		// notifications are sent through the agentx master only
+		agent, err := NewSnmpAgent(c.SnmpListen, c.SnmpCommunity, handler, subtrees)
+		if err != nil {
+			return fmt.Errorf("failed to start SNMP agent: %w", err)
+		}
+		if c.SnmpUsers != "" {
+			config, err := LoadSnmpV3Config(c.SnmpUsers)
+			if err != nil {
+				return fmt.Errorf("failed to load SNMPv3 users: %w", err)
+			}
+			if err := agent.EnableUSM(config, c.SnmpEngineFile); err != nil {
+				return fmt.Errorf("failed to enable SNMPv3: %w", err)
+			}
+		}
+		go func() {
+			if err := agent.Serve(ctx); err != nil {
+				errs <- fmt.Errorf("failed to serve SNMP requests: %w", err)
+			}
+		}()
+		log.Printf("[INFO] SNMP agent listening on %s, waiting for requests", agent.Addr())
+	} else {
+		snmpclient, err := agentx.Dial("unix", c.SnmpMasterSock)
+		if err != nil {
+			return fmt.Errorf("failed to connect to SNMP master: %w", err)
+		}
+		snmpclient.Timeout = 1 * time.Minute
+		snmpclient.ReconnectInterval = 1 * time.Second
+
+		sessions, err := handler.Register(c.SnmpPriority, snmpclient, subtrees)
+		if err != nil {
+			return fmt.Errorf("failed to register SNMP handler: %w", err)
+		}
+		if c.SnmpNotifications {
+			// sent on the session of the BGP4-MIB, the first subtree
+			notifier := NewAgentxNotifier(sessions[0])
+			go notifier.Run(ctx)
+			handler.Notifier = notifier
+		}
+		log.Printf("[INFO] agentx started, waiting for requests")
 	}
 
-	log.Printf("[INFO] agentx started, waiting for requests")
+	if c.HttpListen != "" {
+		server, err := NewHTTPServer(c.HttpListen, handler)
+		if err != nil {
+			return fmt.Errorf("failed to start HTTP server: %w", err)
+		}
+		go func() {
+			if err := server.Serve(ctx); err != nil {
+				errs <- fmt.Errorf("failed to serve HTTP requests: %w", err)
+			}
+		}()
+		log.Printf("[INFO] HTTP server listening on %s", server.Addr())
+	}
 
-	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	var watcher *BirdLogWatcher
+	var events <-chan string
+	if c.BirdEcho {
+		watcher = NewBirdLogWatcher(CLI.BirdSock)
+		if recorder != nil {
+			watcher.Transcript = recorder.transcript(true)
+		}
+		events = watcher.Events
+		go watcher.Run(ctx)
+	}
 
-	ticker := time.NewTicker(CLI.BirdRefreshInterval)
+	ticker := time.NewTicker(c.BirdRefreshInterval)
 	defer ticker.Stop()
+	lastRefresh := time.Now()
 
 	for {
 		select {
 		case <-ticker.C:
-			if err := handler.Refresh(); err != nil {
+			// while bird's log reports state changes polling is a slow
+			// safety net only
+			if watcher != nil && watcher.StateChanges() && time.Since(lastRefresh) < c.BirdResyncInterval {
+				continue
+			}
+			if c.BirdIncremental && time.Since(lastRefresh) < c.BirdResyncInterval {
+				if err := handler.RefreshIncremental(ctx); err != nil {
+					log.Printf("[ERROR] Failed to refresh BGP data: %v", err)
+				}
+				continue
+			}
+			lastRefresh = time.Now()
+			if err := handler.Refresh(ctx); err != nil {
+				log.Printf("[ERROR] Failed to refresh BGP data: %v", err)
+			}
+		case name := <-events:
+			// a state change comes with a burst of messages
+			names := []string{name}
+		drain:
+			for {
+				select {
+				case name := <-events:
+					names = append(names, name)
+				default:
+					break drain
+				}
+			}
+			if err := handler.RefreshProtocols(ctx, names); err != nil {
 				log.Printf("[ERROR] Failed to refresh BGP data: %v", err)
 			}
-		case sig := <-sigChan:
-			log.Printf("[INFO] Received signal %v, shutting down", sig)
-			return
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			log.Printf("[INFO] Received signal, shutting down")
+			return nil
 		}
 	}
 }
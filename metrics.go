@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsHandler serves /metrics in the Prometheus text exposition format.
+type metricsHandler struct {
+	h *BirdBGPHandler
+}
+
+func (m metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	writeMetrics(b, m.h, m.h.current())
+	if err := b.Flush(); err != nil {
+		log.Printf("[ERROR] Failed to write metrics: %v", err)
+	}
+}
+
+// metric is a family of samples, each sample has a value for every label.
+type metric struct {
+	name    string
+	help    string
+	typ     string // gauge or counter
+	labels  []string
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.samples = append(m.samples, metricSample{labels: labels, value: value})
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (m *metric) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+	for _, sample := range m.samples {
+		w.WriteString(m.name)
+		if len(m.labels) > 0 {
+			w.WriteByte('{')
+			for i, label := range m.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, `%s="%s"`, label, metricLabelEscaper.Replace(sample.labels[i]))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+		w.WriteByte('\n')
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// writeMetrics renders snapshot. Values match the SNMP objects, states use
+// the same enumerations as bgpPeerState and birdDaemonState.
+func writeMetrics(w *bufio.Writer, h *BirdBGPHandler, snapshot *birdSnapshot) {
+	status := snapshot.status
+
+	info := &metric{name: "bird_info", help: "BIRD version and router ID.", typ: "gauge", labels: []string{"version", "router_id", "hostname"}}
+	if !snapshot.birdT.IsZero() {
+		routerID := ""
+		if status.RouterId != nil {
+			routerID = status.RouterId.String()
+		}
+		info.add(1, status.Version, routerID, status.Hostname)
+	}
+	daemonState := &metric{name: "bird_daemon_state", help: "BIRD daemon state: running(1), reconfiguring(2), shuttingDown(3), gracefulRestart(4) or unknown(0).", typ: "gauge"}
+	daemonState.add(float64(birdDaemonState(status)))
+	uptime := &metric{name: "bird_uptime_seconds", help: "Time since BIRD was started.", typ: "gauge"}
+	if !status.ServerTime.IsZero() && !status.LastReboot.IsZero() {
+		uptime.add(status.ServerTime.Sub(status.LastReboot).Seconds())
+	}
+	reconfigurationAge := &metric{name: "bird_reconfiguration_age_seconds", help: "Time since BIRD was last reconfigured.", typ: "gauge"}
+	if !status.ServerTime.IsZero() && !status.LastReconfiguration.IsZero() {
+		reconfigurationAge.add(status.ServerTime.Sub(status.LastReconfiguration).Seconds())
+	}
+	dataAge := &metric{name: "bird_data_age_seconds", help: "Time since the last successful refresh of the data.", typ: "gauge"}
+	dataAge.add(h.dataAge(snapshot).Seconds())
+	dataStale := &metric{name: "bird_data_stale", help: "Whether the data is older than the maximum data age.", typ: "gauge"}
+	dataStale.add(boolToFloat(h.stale(snapshot)))
+
+	peerLabels := []string{"protocol", "neighbor"}
+	peerState := &metric{name: "bird_bgp_peer_state", help: "BGP session state as bgpPeerState: idle(1), connect(2), active(3), opensent(4), openconfirm(5) or established(6).", typ: "gauge", labels: peerLabels}
+	peerUptime := &metric{name: "bird_bgp_peer_established_seconds", help: "Time since the BGP session was established, 0 unless it is.", typ: "gauge", labels: peerLabels}
+	peerLocalAs := &metric{name: "bird_bgp_peer_local_as", help: "Local AS number of the BGP session.", typ: "gauge", labels: peerLabels}
+	peerRemoteAs := &metric{name: "bird_bgp_peer_remote_as", help: "AS number of the BGP neighbor.", typ: "gauge", labels: peerLabels}
+
+	channelLabels := []string{"protocol", "neighbor", "channel"}
+	imported := &metric{name: "bird_bgp_channel_imported_routes", help: "Routes imported through the channel.", typ: "gauge", labels: channelLabels}
+	filtered := &metric{name: "bird_bgp_channel_filtered_routes", help: "Routes filtered by the import filter of the channel.", typ: "gauge", labels: channelLabels}
+	exported := &metric{name: "bird_bgp_channel_exported_routes", help: "Routes exported through the channel.", typ: "gauge", labels: channelLabels}
+	preferred := &metric{name: "bird_bgp_channel_preferred_routes", help: "Imported routes preferred in the table of the channel.", typ: "gauge", labels: channelLabels}
+
+	for _, proto := range snapshot.protocols {
+		neighbor := ""
+		if proto.NeighborAddress != nil {
+			neighbor = proto.NeighborAddress.String()
+		}
+		peerState.add(float64(bgpState(proto.State)), proto.Name, neighbor)
+		established := 0.0
+		if proto.Up {
+			established = time.Since(proto.Since).Seconds()
+		}
+		peerUptime.add(established, proto.Name, neighbor)
+		peerLocalAs.add(float64(proto.LocalAs), proto.Name, neighbor)
+		peerRemoteAs.add(float64(proto.NeighborAs), proto.Name, neighbor)
+
+		for _, name := range sortedChannelNames(proto) {
+			channel := proto.Channels[name]
+			imported.add(float64(channel.Imported), proto.Name, neighbor, name)
+			filtered.add(float64(channel.Filtered), proto.Name, neighbor, name)
+			exported.add(float64(channel.Exported), proto.Name, neighbor, name)
+			preferred.add(float64(channel.Preferred), proto.Name, neighbor, name)
+		}
+	}
+
+	for _, m := range []*metric{
+		info, daemonState, uptime, reconfigurationAge, dataAge, dataStale,
+		peerState, peerUptime, peerLocalAs, peerRemoteAs,
+		imported, filtered, exported, preferred,
+	} {
+		m.write(w)
+	}
+}
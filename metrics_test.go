@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	server, err := NewHTTPServer("127.0.0.1:0", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Serve(ctx)
+
+	response, err := http.Get("http://" + server.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	b, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := map[string]bool{}
+	for _, line := range strings.Split(string(b), "\n") {
+		lines[line] = true
+	}
+	for _, want := range []string{
+		`bird_info{version="2.15.1",router_id="192.168.32.79",hostname="infra2"} 1`,
+		`bird_daemon_state 1`,
+		`bird_data_stale 0`,
+		`# TYPE bird_bgp_peer_state gauge`,
+		`bird_bgp_peer_state{protocol="ber1_gw1",neighbor="192.168.32.1"} 6`,
+		`bird_bgp_peer_state{protocol="xxx_gw1",neighbor="192.168.32.253"} 3`,
+		`bird_bgp_peer_established_seconds{protocol="xxx_gw1",neighbor="192.168.32.253"} 0`,
+		`bird_bgp_peer_remote_as{protocol="ber1_gw1",neighbor="192.168.32.1"} 64846`,
+		`bird_bgp_channel_imported_routes{protocol="ber1_gw1",neighbor="192.168.32.1",channel="ipv4"} 21`,
+		`bird_bgp_channel_preferred_routes{protocol="ber1_gw1",neighbor="192.168.32.1",channel="ipv4"} 21`,
+	} {
+		if !lines[want] {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func Test_metricWrite(t *testing.T) {
+	m := &metric{name: "test", help: "Test.", typ: "gauge", labels: []string{"name"}}
+	m.add(1.5, "a \"quoted\"\nname\\")
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	m.write(w)
+	w.Flush()
+	want := "# HELP test Test.\n# TYPE test gauge\ntest{name=\"a \\\"quoted\\\"\\nname\\\\\"} 1.5\n"
+	if b.String() != want {
+		t.Errorf("write() = %q, want %q", b.String(), want)
+	}
+}
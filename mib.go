@@ -0,0 +1,182 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/posteo/go-agentx/value"
+)
+
+//go:embed BGP4-MIB.mib BIRD-MIB.mib
+var bundledMibs embed.FS
+
+// mibRoots are the OIDs of the imported parents of the bundled MIBs.
+var mibRoots = map[string]value.OID{
+	"mib-2":          {1, 3, 6, 1, 2, 1},
+	"enterprises":    {1, 3, 6, 1, 4, 1},
+	"netSnmpPlaypen": {1, 3, 6, 1, 4, 1, 8072, 9999},
+}
+
+// mibDefinitionKeywords are the macros of definitions assigning an OID.
+var mibDefinitionKeywords = map[string]bool{
+	"OBJECT-TYPE":        true,
+	"OBJECT-IDENTITY":    true,
+	"MODULE-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"IDENTIFIER":         true, // OBJECT IDENTIFIER
+}
+
+var (
+	mibStrings  = regexp.MustCompile(`"[^"]*"`)
+	mibComments = regexp.MustCompile(`--[^\n]*`)
+	mibTokens   = regexp.MustCompile(`::=|[{}(),;]|[A-Za-z0-9-]+`)
+)
+
+// mibAssignment is the parent and the arcs below it of a definition,
+// e.g. bgp and 1 for "bgpVersion OBJECT-TYPE ... ::= { bgp 1 }".
+type mibAssignment struct {
+	parent string
+	arcs   value.OID
+}
+
+// parseMibAssignments returns the OID assignments of a SMIv2 module. It is
+// no SMI parser, just enough to name the objects of well-formed modules.
+func parseMibAssignments(src string) map[string]mibAssignment {
+	src = mibComments.ReplaceAllString(mibStrings.ReplaceAllString(src, `""`), "")
+	tokens := mibTokens.FindAllString(src, -1)
+	assignments := map[string]mibAssignment{}
+	for i, token := range tokens {
+		if token != "::=" || i+3 >= len(tokens) || tokens[i+1] != "{" {
+			continue
+		}
+		// the nearest definition keyword before ::= names the object
+		name := ""
+		for j := i - 1; j > 0; j-- {
+			if !mibDefinitionKeywords[tokens[j]] {
+				continue
+			}
+			k := j - 1
+			if tokens[j] == "IDENTIFIER" {
+				if tokens[k] != "OBJECT" || k == 0 {
+					continue
+				}
+				k--
+			}
+			if c := tokens[k][0]; c >= 'a' && c <= 'z' {
+				name = tokens[k]
+				break
+			}
+		}
+		if name == "" {
+			continue
+		}
+		assignment := mibAssignment{parent: tokens[i+2]}
+		for _, arc := range tokens[i+3:] {
+			if arc == "}" {
+				break
+			}
+			n, err := strconv.ParseUint(arc, 10, 32)
+			if err != nil {
+				assignment.arcs = nil
+				break
+			}
+			assignment.arcs = append(assignment.arcs, uint32(n))
+		}
+		if len(assignment.arcs) > 0 {
+			assignments[name] = assignment
+		}
+	}
+	return assignments
+}
+
+// MibNames resolves OIDs to the names of MIB objects.
+type MibNames struct {
+	names map[string]string    // OID in dotted notation to name
+	oids  map[string]value.OID // name to OID
+}
+
+// LoadBundledMibNames returns the names of the objects of the MIBs shipped
+// with bird2snmp.
+func LoadBundledMibNames() (*MibNames, error) {
+	assignments := map[string]mibAssignment{}
+	for _, file := range []string{"BGP4-MIB.mib", "BIRD-MIB.mib"} {
+		src, err := bundledMibs.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, assignment := range parseMibAssignments(string(src)) {
+			assignments[name] = assignment
+		}
+	}
+
+	m := &MibNames{names: map[string]string{}, oids: map[string]value.OID{}}
+	var resolve func(name string, depth int) value.OID
+	resolve = func(name string, depth int) value.OID {
+		if oid, ok := mibRoots[name]; ok {
+			return oid
+		}
+		if oid, ok := m.oids[name]; ok {
+			return oid
+		}
+		assignment, ok := assignments[name]
+		if !ok || depth > berMaxOIDLength {
+			return nil
+		}
+		parent := resolve(assignment.parent, depth+1)
+		if parent == nil {
+			return nil
+		}
+		oid := append(append(value.OID{}, parent...), assignment.arcs...)
+		m.oids[name] = oid
+		return oid
+	}
+	for name := range assignments {
+		if oid := resolve(name, 0); oid != nil {
+			m.names[oid.String()] = name
+		}
+	}
+	if len(m.names) == 0 {
+		return nil, fmt.Errorf("no objects found in the bundled MIBs")
+	}
+	return m, nil
+}
+
+// Name returns the name of the object oid belongs to followed by the
+// instance suffix, e.g. bgpPeerState.192.168.32.1, or oid itself if it is
+// not within a known object.
+func (m *MibNames) Name(oid value.OID) string {
+	for n := len(oid); n > 0; n-- {
+		name, ok := m.names[oid[:n].String()]
+		if !ok {
+			continue
+		}
+		if n == len(oid) {
+			return name
+		}
+		return name + "." + oid[n:].String()
+	}
+	return oid.String()
+}
+
+// Parse returns the OID of a MIB object name or of an OID in dotted
+// notation, empty for an empty string.
+func (m *MibNames) Parse(s string) (value.OID, error) {
+	s = strings.TrimPrefix(s, ".")
+	if s == "" {
+		return value.OID{}, nil
+	}
+	if oid, ok := m.oids[s]; ok {
+		return oid, nil
+	}
+	oid, err := value.ParseOID(s)
+	if err != nil {
+		return nil, fmt.Errorf("neither an OID nor a known MIB object: %s", s)
+	}
+	return oid, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+var (
+	oidSysUpTime   = value.OID{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	oidSnmpTrapOID = value.OID{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+)
+
+// notifyQueueSize bounds the notifications waiting for the master, further
+// ones are dropped rather than delaying refreshes.
+const notifyQueueSize = 256
+
+// AgentxNotifier sends notifications to the agentx master on an existing
+// session. Notify only queues them, Run sends them one after the other so
+// a stalled master never blocks the refresh reporting the transitions.
+type AgentxNotifier struct {
+	session *agentx.Session
+	started time.Time
+	queue   chan pdu.Variables
+}
+
+func NewAgentxNotifier(session *agentx.Session) *AgentxNotifier {
+	return &AgentxNotifier{session: session, started: time.Now(), queue: make(chan pdu.Variables, notifyQueueSize)}
+}
+
+// Notify queues the trapOID notification with the provided varbinds,
+// prefixed by sysUpTime.0 and snmpTrapOID.0.
+func (n *AgentxNotifier) Notify(trapOID value.OID, variables pdu.Variables) error {
+	packet := pdu.Variables{}
+	packet.Add(oidSysUpTime, pdu.VariableTypeTimeTicks, time.Since(n.started))
+	packet.Add(oidSnmpTrapOID, pdu.VariableTypeObjectIdentifier, trapOID.String())
+	packet = append(packet, variables...)
+	select {
+	case n.queue <- packet:
+		return nil
+	default:
+		return newSNMPError("notify", fmt.Errorf("%d notifications pending, dropped", notifyQueueSize))
+	}
+}
+
+// Run sends the queued notifications until ctx is cancelled.
+func (n *AgentxNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case variables := <-n.queue:
+			if err := n.session.Notify(variables); err != nil {
+				log.Printf("[ERROR] Failed to send notification: %v", newSNMPError("notify", err))
+			}
+		}
+	}
+}
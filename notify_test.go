@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// fakeAgentxMaster accepts a single subagent, answers its requests with
+// session 7 and passes them on to packets, as well as its responses to
+// requests of the master. Notify requests are left unanswered while
+// stalled.
+type fakeAgentxMaster struct {
+	listener net.Listener
+	packets  chan *agentxTestPacket
+	conns    chan net.Conn
+	stalled  bool
+}
+
+func startFakeAgentxMaster(t *testing.T, path string, stalled bool) *fakeAgentxMaster {
+	t.Helper()
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &fakeAgentxMaster{listener: listener, packets: make(chan *agentxTestPacket, 16), conns: make(chan net.Conn, 1), stalled: stalled}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		m.conns <- conn
+		for {
+			headerBytes := make([]byte, pdu.HeaderSize)
+			if _, err := io.ReadFull(conn, headerBytes); err != nil {
+				return
+			}
+			header := &pdu.Header{}
+			header.UnmarshalBinary(headerBytes)
+			payload := make([]byte, header.PayloadLength)
+			if _, err := io.ReadFull(conn, payload); err != nil {
+				return
+			}
+			m.packets <- &agentxTestPacket{header: header, payload: payload}
+			if header.Type == pdu.TypeResponse || header.Type == pdu.TypeNotify && m.stalled {
+				continue
+			}
+			response := &pdu.HeaderPacket{
+				Header: &pdu.Header{Version: 1, SessionID: 7, TransactionID: header.TransactionID, PacketID: header.PacketID},
+				Packet: &pdu.Response{},
+			}
+			b, _ := response.MarshalBinary()
+			conn.Write(b)
+		}
+	}()
+	return m
+}
+
+// getNext sends a GetNext request for the ranges starting at the oids
+// included up to end to the subagent of session 7.
+func (m *fakeAgentxMaster) getNext(t *testing.T, end value.OID, oids ...value.OID) {
+	t.Helper()
+	var payload []byte
+	for _, oid := range oids {
+		from, to := &pdu.ObjectIdentifier{}, &pdu.ObjectIdentifier{}
+		from.SetIdentifier(oid)
+		from.SetInclude(true)
+		to.SetIdentifier(end)
+		for _, o := range []*pdu.ObjectIdentifier{from, to} {
+			b, err := o.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload = append(payload, b...)
+		}
+	}
+	header := &pdu.Header{Version: 1, Type: pdu.TypeGetNext, SessionID: 7, TransactionID: 1, PacketID: 1000, PayloadLength: uint32(len(payload))}
+	b, err := header.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := <-m.conns
+	m.conns <- conn
+	if _, err := conn.Write(append(b, payload...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// agentxTestPacket is a packet received by the master.
+type agentxTestPacket struct {
+	header  *pdu.Header
+	payload []byte
+}
+
+// agentxTestVariables decodes a VarBindList. go-agentx decodes the names
+// and octet strings only: Variables.UnmarshalBinary fails on OID values and
+// Variable.UnmarshalBinary reads numbers from the wrong offset.
+func agentxTestVariables(t *testing.T, b []byte) []pdu.Variable {
+	t.Helper()
+	var variables []pdu.Variable
+	for len(b) > 0 {
+		v := pdu.Variable{}
+		if err := v.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		size := 4 + 4 + 4*int(b[4])
+		switch v.Type {
+		case pdu.VariableTypeInteger:
+			v.Value = int32(binary.LittleEndian.Uint32(b[size:]))
+			size += 4
+		case pdu.VariableTypeCounter32, pdu.VariableTypeGauge32:
+			v.Value = binary.LittleEndian.Uint32(b[size:])
+			size += 4
+		case pdu.VariableTypeTimeTicks:
+			v.Value = time.Duration(binary.LittleEndian.Uint32(b[size:])) * 10 * time.Millisecond
+			size += 4
+		case pdu.VariableTypeCounter64:
+			v.Value = binary.LittleEndian.Uint64(b[size:])
+			size += 8
+		case pdu.VariableTypeOctetString, pdu.VariableTypeIPAddress, pdu.VariableTypeOpaque:
+			n := int(binary.LittleEndian.Uint32(b[size:]))
+			size += 4 + (n+3)/4*4
+		case pdu.VariableTypeObjectIdentifier:
+			size += 4 + 4*int(b[size])
+		}
+		variables = append(variables, v)
+		b = b[size:]
+	}
+	return variables
+}
+
+// next returns the next packet of type typ received by the master.
+func (m *fakeAgentxMaster) next(t *testing.T, typ pdu.Type) *agentxTestPacket {
+	t.Helper()
+	for {
+		select {
+		case packet := <-m.packets:
+			if packet.header.Type == typ {
+				return packet
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s packet received", typ)
+		}
+	}
+}
+
+func startTestNotifier(t *testing.T, stalled bool) (*fakeAgentxMaster, *AgentxNotifier) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "master")
+	master := startFakeAgentxMaster(t, path, stalled)
+	t.Cleanup(func() { master.listener.Close() })
+
+	client, err := agentx.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	client.Timeout = time.Minute
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	sessions, err := h.Register(127, client, []value.OID{oidBgp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	notifier := NewAgentxNotifier(sessions[0])
+	go notifier.Run(ctx)
+	return master, notifier
+}
+
+func TestAgentxNotifier(t *testing.T) {
+	master, notifier := startTestNotifier(t, false)
+	master.next(t, pdu.TypeRegister)
+
+	transition := bgpPeerTransition{trapOID: oidBgpBackwardTransNotification, proto: ProtocolBGPStatus{
+		Name: "gw1", State: "Active", NeighborAddress: net.ParseIP("192.168.32.1"), LastError: "Received: Hold timer expired",
+	}}
+	variables, _ := transition.variables()
+	if err := notifier.Notify(transition.trapOID, variables); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := master.next(t, pdu.TypeNotify)
+	if notify.header.SessionID != 7 {
+		t.Errorf("Notify session = %d, want the registering session 7", notify.header.SessionID)
+	}
+	got := agentxTestVariables(t, notify.payload)
+	peer := value.OID{192, 168, 32, 1}
+	want := []struct {
+		oid     value.OID
+		varType pdu.VariableType
+		value   interface{}
+	}{
+		{oidSysUpTime, pdu.VariableTypeTimeTicks, nil},
+		{oidSnmpTrapOID, pdu.VariableTypeObjectIdentifier, oidBgpBackwardTransNotification},
+		{append(append(value.OID{}, oidBgpPeerRemoteAddr...), peer...), pdu.VariableTypeIPAddress, net.IP{192, 168, 32, 1}},
+		{append(append(value.OID{}, oidBgpPeerLastError...), peer...), pdu.VariableTypeOctetString, string([]byte{4, 0})},
+		{append(append(value.OID{}, oidBgpPeerState...), peer...), pdu.VariableTypeInteger, int32(3)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Notify variables = %v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if compareOids(got[i].Name.GetIdentifier(), w.oid) != 0 || got[i].Type != w.varType {
+			t.Errorf("variable %d = %v %s, want %v %s", i, got[i].Name.GetIdentifier(), got[i].Type, w.oid, w.varType)
+		}
+		if w.value != nil && !reflect.DeepEqual(got[i].Value, w.value) {
+			t.Errorf("variable %d value = %#v, want %#v", i, got[i].Value, w.value)
+		}
+	}
+}
+
+func TestAgentxNotifierStalled(t *testing.T) {
+	master, notifier := startTestNotifier(t, true)
+	master.next(t, pdu.TypeRegister)
+
+	// the master never answers, the first notification blocks the sender
+	// and the others queue up until the queue is full
+	start := time.Now()
+	var err error
+	for i := 0; i <= notifyQueueSize+1 && err == nil; i++ {
+		err = notifier.Notify(oidBgpEstablishedNotification, nil)
+	}
+	if err == nil {
+		t.Error("Notify() never reported dropped notifications")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Notify() blocked for %s", elapsed)
+	}
+}
@@ -19,25 +19,116 @@ import (
 // Last reconfiguration on 2024-10-13 09:25:06.844
 // Daemon is up and running
 type ShowStatus struct {
-	RouterId net.IP
-	Hostname string
+	Version             string    `json:"version"`
+	RouterId            net.IP    `json:"routerId"`
+	Hostname            string    `json:"hostname"`
+	ServerTime          time.Time `json:"serverTime"`
+	LastReboot          time.Time `json:"lastReboot"`
+	LastReconfiguration time.Time `json:"lastReconfiguration"`
+	DaemonState         string    `json:"daemonState"`     // the daemon state line, e.g. "Daemon is up and running"
+	GracefulRestart     bool      `json:"gracefulRestart"` // graceful restart recovery in progress
+}
+
+// statusTimeLayout is the layout of timestamps printed by show status.
+const statusTimeLayout = "2006-01-02 15:04:05.999"
+
+// daemonStateLines are the lines show status ends with.
+var daemonStateLines = map[string]bool{
+	"Daemon is up and running":    true,
+	"Reconfiguration in progress": true,
+	"Shutdown in progress":        true,
+}
+
+func parseStatusTime(in string) time.Time {
+	t, err := time.ParseInLocation(statusTimeLayout, in, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
 }
 
 func ParseShowStatus(in string) ShowStatus {
 	status := ShowStatus{}
 	for _, line := range strings.Split(in, "\n") {
-		routerIdPref := "Router ID is "
-		if strings.HasPrefix(line, routerIdPref) {
-			status.RouterId = net.ParseIP(strings.TrimSpace(line[len(routerIdPref):]))
+		line = strings.TrimSpace(line)
+		if v, ok := lineValue(line, "BIRD "); ok && !strings.HasSuffix(v, " ready.") {
+			status.Version = v
+		}
+		if v, ok := lineValue(line, "Router ID is "); ok {
+			status.RouterId = net.ParseIP(v)
+		}
+		if v, ok := lineValue(line, "Hostname is "); ok {
+			status.Hostname = v
+		}
+		if v, ok := lineValue(line, "Current server time is "); ok {
+			status.ServerTime = parseStatusTime(v)
+		}
+		if v, ok := lineValue(line, "Last reboot on "); ok {
+			status.LastReboot = parseStatusTime(v)
+		}
+		if v, ok := lineValue(line, "Last reconfiguration on "); ok {
+			status.LastReconfiguration = parseStatusTime(v)
 		}
-		hostnamePref := "Hostname is "
-		if strings.HasPrefix(line, hostnamePref) {
-			status.Hostname = strings.TrimSpace(line[len(hostnamePref):])
+		if line == "Graceful restart recovery in progress" {
+			status.GracefulRestart = true
+		}
+		if daemonStateLines[line] {
+			status.DaemonState = line
 		}
 	}
 	return status
 }
 
+// $ sudo birdc show protocols
+// BIRD 2.15.1 ready.
+// Name       Proto      Table      State  Since         Info
+// device1    Device     ---        up     2024-10-12 20:41:10
+// xxx_gw1    BGP        ---        start  2024-10-13 09:25:06  Active        Socket: No route to host
+type ProtocolStatus struct {
+	Name  string    `json:"name"`
+	Proto string    `json:"proto"`
+	Table string    `json:"table"`
+	State string    `json:"state"`
+	Since time.Time `json:"since"`
+	Info  string    `json:"info"`
+}
+
+// parseProtocolLine parses the summary line printed for every protocol by
+// show protocols and show protocols all.
+func parseProtocolLine(line string) (ProtocolStatus, bool) {
+	items := strings.Fields(line)
+	if len(items) < 6 {
+		return ProtocolStatus{}, false
+	}
+	if items[0] == "Name" && items[1] == "Proto" {
+		return ProtocolStatus{}, false
+	}
+	proto := ProtocolStatus{Name: items[0], Proto: items[1], State: items[3]}
+	if items[2] != "---" {
+		proto.Table = items[2]
+	}
+	if t, err := time.ParseInLocation(time.DateTime, items[4]+" "+items[5], time.Local); err == nil {
+		proto.Since = t
+	}
+	proto.Info = strings.Join(items[6:], " ")
+	return proto, true
+}
+
+// ParseShowProtocols returns every protocol of show protocols or show
+// protocols all output in bird's order, whatever its type.
+func ParseShowProtocols(in string) []ProtocolStatus {
+	protocols := []ProtocolStatus{}
+	for _, line := range strings.Split(in, "\n") {
+		if len(line) < 1 || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if proto, ok := parseProtocolLine(line); ok {
+			protocols = append(protocols, proto)
+		}
+	}
+	return protocols
+}
+
 // pnz2_gw1   BGP        ---        up     2024-10-12 19:14:52  Established
 //
 //	BGP state:          Established
@@ -80,21 +171,187 @@ func ParseShowStatus(in string) ShowStatus {
 //	  BGP Next hop:   169.254.153.77
 
 type ProtocolBGPChannel struct {
-	Name      string
-	Imported  int
-	Exported  int
-	Preferred int
+	Name         string   `json:"name"`
+	Afi          uint16   `json:"afi"`
+	Safi         uint8    `json:"safi"`
+	State        string   `json:"state"`
+	Table        string   `json:"table"`
+	Preference   int      `json:"preference"`
+	InputFilter  string   `json:"inputFilter"`
+	OutputFilter string   `json:"outputFilter"`
+	Imported     int      `json:"imported"`
+	Filtered     int      `json:"filtered"`
+	Exported     int      `json:"exported"`
+	Preferred    int      `json:"preferred"`
+	ImportLimit  int      `json:"importLimit"`
+	BGPNextHop   []net.IP `json:"bgpNextHop"`
+
+	ImportUpdates   RouteChangeStats `json:"importUpdates"`
+	ImportWithdraws RouteChangeStats `json:"importWithdraws"`
+	ExportUpdates   RouteChangeStats `json:"exportUpdates"`
+	ExportWithdraws RouteChangeStats `json:"exportWithdraws"`
+}
+
+// RouteChangeStats is a row of bird's "Route change stats" matrix keyed by
+// column name, cells bird prints as "---" are missing.
+type RouteChangeStats map[string]uint64
+
+// routeChangeColumns are the columns of the route change stats matrix.
+var routeChangeColumns = []string{"received", "rejected", "filtered", "ignored", "accepted"}
+
+func parseRouteChangeStats(in string) RouteChangeStats {
+	stats := RouteChangeStats{}
+	for i, field := range strings.Fields(in) {
+		if i >= len(routeChangeColumns) {
+			break
+		}
+		if v, err := strconv.ParseUint(field, 10, 64); err == nil {
+			stats[routeChangeColumns[i]] = v
+		}
+	}
+	return stats
+}
+
+// channelAfiSafi maps bird channel names to their AFI and SAFI.
+var channelAfiSafi = map[string]struct {
+	afi  uint16
+	safi uint8
+}{
+	"ipv4":      {1, 1},
+	"ipv4-mc":   {1, 2},
+	"ipv4-mpls": {1, 4},
+	"vpn4":      {1, 128},
+	"vpn4-mpls": {1, 128},
+	"vpn4-mc":   {1, 129},
+	"flow4":     {1, 133},
+	"ipv6":      {2, 1},
+	"ipv6-mc":   {2, 2},
+	"ipv6-mpls": {2, 4},
+	"vpn6":      {2, 128},
+	"vpn6-mpls": {2, 128},
+	"vpn6-mc":   {2, 129},
+	"flow6":     {2, 133},
+}
+
+// newProtocolBGPChannel returns a channel named name, AFI and SAFI stay
+// zero for channel types unknown to BGP.
+func newProtocolBGPChannel(name string) ProtocolBGPChannel {
+	afiSafi := channelAfiSafi[name]
+	return ProtocolBGPChannel{Name: name, Afi: afiSafi.afi, Safi: afiSafi.safi}
+}
+
+// parseChannelLine fills channel from a single line of a "Channel" block.
+func parseChannelLine(line string, channel *ProtocolBGPChannel) {
+	if v, ok := lineValue(line, "    State:"); ok {
+		channel.State = v
+	}
+	if v, ok := lineValue(line, "    Table:"); ok {
+		channel.Table = v
+	}
+	if v, ok := lineValue(line, "    Preference:"); ok {
+		if preference, err := strconv.Atoi(v); err == nil {
+			channel.Preference = preference
+		}
+	}
+	if v, ok := lineValue(line, "    Input filter:"); ok {
+		channel.InputFilter = v
+	}
+	if v, ok := lineValue(line, "    Output filter:"); ok {
+		channel.OutputFilter = v
+	}
+	if v, ok := lineValue(line, "    Routes:"); ok {
+		for _, statpart := range strings.Split(v, ",") {
+			statpartItems := strings.SplitN(strings.TrimSpace(statpart), " ", 2)
+			if len(statpartItems) != 2 {
+				continue
+			}
+			statValue, err := strconv.Atoi(statpartItems[0])
+			if err != nil {
+				continue
+			}
+			switch statpartItems[1] {
+			case "imported":
+				channel.Imported = statValue
+			case "filtered":
+				channel.Filtered = statValue
+			case "exported":
+				channel.Exported = statValue
+			case "preferred":
+				channel.Preferred = statValue
+			}
+		}
+	}
+	if v, ok := lineValue(line, "    Import limit:"); ok {
+		// the limit may be followed by " [HIT]" once it was reached
+		limit, _, _ := strings.Cut(v, " ")
+		if limit, err := strconv.Atoi(limit); err == nil {
+			channel.ImportLimit = limit
+		}
+	}
+	if v, ok := lineValue(line, "      Import updates:"); ok {
+		channel.ImportUpdates = parseRouteChangeStats(v)
+	}
+	if v, ok := lineValue(line, "      Import withdraws:"); ok {
+		channel.ImportWithdraws = parseRouteChangeStats(v)
+	}
+	if v, ok := lineValue(line, "      Export updates:"); ok {
+		channel.ExportUpdates = parseRouteChangeStats(v)
+	}
+	if v, ok := lineValue(line, "      Export withdraws:"); ok {
+		channel.ExportWithdraws = parseRouteChangeStats(v)
+	}
+	if v, ok := lineValue(line, "    BGP Next hop:"); ok {
+		// IPv6 sessions may list a link-local next hop after the global one
+		channel.BGPNextHop = nil
+		for _, field := range strings.Fields(v) {
+			if ip := net.ParseIP(field); ip != nil {
+				channel.BGPNextHop = append(channel.BGPNextHop, ip)
+			}
+		}
+	}
 }
 
 type ProtocolBGPStatus struct {
-	Name            string
-	Table           string
-	Up              bool
-	Since           time.Time
-	State           string
-	NeighborAddress net.IP
-	LocalAs         int
-	Channels        map[string]ProtocolBGPChannel
+	Name              string                        `json:"name"`
+	Table             string                        `json:"table"`
+	Up                bool                          `json:"up"`
+	ProtoState        string                        `json:"protoState"`
+	Since             time.Time                     `json:"since"`
+	State             string                        `json:"state"`
+	NeighborAddress   net.IP                        `json:"neighborAddress"`
+	NeighborInterface string                        `json:"neighborInterface"`
+	NeighborPort      int                           `json:"neighborPort"`
+	NeighborAs        uint32                        `json:"neighborAs"`
+	NeighborId        net.IP                        `json:"neighborId"`
+	LocalAs           uint32                        `json:"localAs"`
+	Session           string                        `json:"session"`
+	SourceAddress     net.IP                        `json:"sourceAddress"`
+	HoldTime          int                           `json:"holdTime"`      // negotiated, seconds, 0 unless established
+	KeepaliveTime     int                           `json:"keepaliveTime"` // negotiated, seconds, 0 unless established
+	LastError         string                        `json:"lastError"`
+	Channels          map[string]ProtocolBGPChannel `json:"channels"`
+}
+
+// parseTimerPeriod returns the period of a bird timer printed as
+// "remaining/period", e.g. 240 for "186.508/240".
+func parseTimerPeriod(in string) (int, bool) {
+	_, period, ok := strings.Cut(in, "/")
+	if !ok {
+		return 0, false
+	}
+	t, err := strconv.Atoi(strings.TrimSpace(period))
+	if err != nil {
+		return 0, false
+	}
+	return t, true
+}
+
+// lineValue returns the trimmed remainder of line if it starts with prefix.
+func lineValue(line string, prefix string) (string, bool) {
+	if !strings.HasPrefix(line, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(prefix):]), true
 }
 
 func ParseShowProtocolsAll(in string) []ProtocolBGPStatus {
@@ -103,6 +360,7 @@ func ParseShowProtocolsAll(in string) []ProtocolBGPStatus {
 
 	protocols := []ProtocolBGPStatus{}
 	var proto *ProtocolBGPStatus
+	var channel ProtocolBGPChannel
 
 	for _, line := range lines {
 		if len(line) < 1 {
@@ -114,18 +372,8 @@ func ParseShowProtocolsAll(in string) []ProtocolBGPStatus {
 		switch state {
 		case "new":
 			state = "parse_any_proto"
-			var items []string
-			for _, item := range strings.Split(line, " ") {
-				item = strings.TrimSpace(item)
-				if item == "" {
-					continue
-				}
-				items = append(items, strings.TrimSpace(item))
-			}
-			if len(items) < 6 {
-				continue
-			}
-			if items[1] != "BGP" {
+			summary, ok := parseProtocolLine(line)
+			if !ok || summary.Proto != "BGP" {
 				continue
 			}
 			state = "parse_bgp_proto"
@@ -134,74 +382,73 @@ func ParseShowProtocolsAll(in string) []ProtocolBGPStatus {
 			}
 			proto = &ProtocolBGPStatus{Channels: map[string]ProtocolBGPChannel{}}
 
-			proto.Name = items[0]
-			if items[2] != "---" {
-				proto.Table = items[2]
+			proto.Name = summary.Name
+			proto.Table = summary.Table
+			proto.ProtoState = summary.State
+			proto.Up = summary.State == "up"
+			proto.Since = summary.Since
+			continue
+		case "parse_bgp_proto":
+			if v, ok := lineValue(line, "  BGP state:"); ok {
+				proto.State = v
 			}
-			if items[3] == "up" {
-				proto.Up = true
+			if v, ok := lineValue(line, "    Neighbor address:"); ok {
+				// link-local neighbors are printed with their interface, e.g. fe80::1%eth0
+				addr, iface, _ := strings.Cut(v, "%")
+				proto.NeighborAddress = net.ParseIP(addr)
+				proto.NeighborInterface = iface
 			}
-			if t, err := time.ParseInLocation(time.DateTime, items[4]+" "+items[5], time.Local); err == nil {
-				proto.Since = t
+			if v, ok := lineValue(line, "    Neighbor port:"); ok {
+				if port, err := strconv.ParseUint(v, 10, 16); err == nil {
+					proto.NeighborPort = int(port)
+				}
 			}
-			continue
-		case "parse_bgp_proto":
-			if strings.HasPrefix(line, "  BGP state:") {
-				items := strings.Split(line, ":")
-				if len(items) > 1 {
-					proto.State = strings.TrimSpace(items[1])
+			if v, ok := lineValue(line, "    Neighbor AS:"); ok {
+				if as, err := strconv.ParseUint(v, 10, 32); err == nil {
+					proto.NeighborAs = uint32(as)
 				}
 			}
-			if strings.HasPrefix(line, "    Neighbor address:") {
-				items := strings.Split(line, ":")
-				if len(items) > 1 {
-					proto.NeighborAddress = net.ParseIP(strings.TrimSpace(items[1]))
+			if v, ok := lineValue(line, "    Local AS:"); ok {
+				if as, err := strconv.ParseUint(v, 10, 32); err == nil {
+					proto.LocalAs = uint32(as)
 				}
 			}
-			if strings.HasPrefix(line, "    Local AS:") {
-				items := strings.Split(line, ":")
-				if len(items) > 1 {
-					localAs, err := strconv.ParseInt(strings.TrimSpace(items[1]), 10, 32)
-					if err == nil {
-						proto.LocalAs = int(localAs)
-					}
+			if v, ok := lineValue(line, "    Neighbor ID:"); ok {
+				proto.NeighborId = net.ParseIP(v)
+			}
+			if v, ok := lineValue(line, "    Session:"); ok {
+				proto.Session = v
+			}
+			if v, ok := lineValue(line, "    Source address:"); ok {
+				proto.SourceAddress = net.ParseIP(v)
+			}
+			if v, ok := lineValue(line, "    Last error:"); ok {
+				proto.LastError = v
+			}
+			if v, ok := lineValue(line, "    Hold timer:"); ok {
+				if t, ok := parseTimerPeriod(v); ok {
+					proto.HoldTime = t
+				}
+			}
+			if v, ok := lineValue(line, "    Keepalive timer:"); ok {
+				if t, ok := parseTimerPeriod(v); ok {
+					proto.KeepaliveTime = t
 				}
 			}
 
-			if strings.HasPrefix(line, "  Channel ") {
-				channelName := strings.SplitN(strings.TrimSpace(line), " ", 2)[1]
-				state = "parse_bgp_proto_channel_" + channelName
+			if name, ok := lineValue(line, "  Channel "); ok {
+				channel = newProtocolBGPChannel(name)
+				proto.Channels[channel.Name] = channel
+				state = "parse_bgp_proto_channel"
 				continue
 			}
-		case "parse_bgp_proto_channel_ipv4":
-			if strings.HasPrefix(line, "    Routes:") {
-				routestatsLine := strings.Split(strings.TrimSpace(line), ":")[1]
-				routestatsLineParts := strings.Split(routestatsLine, ",")
-				channelstat := ProtocolBGPChannel{Name: "ipv4"}
-				for _, statpart := range routestatsLineParts {
-					statpartItems := strings.SplitN(strings.TrimSpace(statpart), " ", 2)
-					if len(statpartItems) != 2 {
-						continue
-					}
-					statValueRaw := statpartItems[0]
-					statName := statpartItems[1]
-					statValue, err := strconv.Atoi(statValueRaw)
-					if err != nil {
-						continue
-					}
-					switch statName {
-					default:
-						continue
-					case "imported":
-						channelstat.Imported = statValue
-					case "exported":
-						channelstat.Exported = statValue
-					case "preferred":
-						channelstat.Preferred = statValue
-					}
-				}
-				proto.Channels[channelstat.Name] = channelstat
+		case "parse_bgp_proto_channel":
+			if name, ok := lineValue(line, "  Channel "); ok {
+				channel = newProtocolBGPChannel(name)
+			} else {
+				parseChannelLine(line, &channel)
 			}
+			proto.Channels[channel.Name] = channel
 		}
 	}
 	if proto != nil {
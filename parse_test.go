@@ -18,7 +18,36 @@ Last reconfiguration on 2024-10-13 09:25:06.844
 Daemon is up and running
 `
 
+var StatusInGracefulRestart = `
+BIRD 2.15.1 ready.
+BIRD 2.15.1
+Router ID is 192.168.32.79
+Hostname is infra2
+Current server time is 2024-10-13 14:39:40.531
+Last reboot on 2024-10-13 14:39:20.002
+Last reconfiguration on 2024-10-13 14:39:20.002
+Graceful restart recovery in progress
+  Waiting for 2 channels to recover
+  Wait timer is 219.529/240
+Daemon is up and running
+`
+
+var StatusInShutdown = `
+BIRD 2.15.1 ready.
+BIRD 2.15.1
+Router ID is 192.168.32.79
+Hostname is infra2
+Current server time is 2024-10-13 14:39:40.531
+Last reboot on 2024-10-12 20:41:10.197
+Last reconfiguration on 2024-10-13 09:25:06.844
+Shutdown in progress
+`
+
 func TestParseShowStatus(t *testing.T) {
+	parseTime := func(in string) time.Time {
+		return mustParseTime(time.ParseInLocation("2006-01-02 15:04:05.000", in, time.Local))
+	}
+
 	type args struct {
 		in string
 	}
@@ -27,7 +56,34 @@ func TestParseShowStatus(t *testing.T) {
 		args args
 		want ShowStatus
 	}{
-		{name: "show status", args: args{in: StatusInDefault}, want: ShowStatus{RouterId: net.IP{192, 168, 32, 79}.To16(), Hostname: "infra2"}},
+		{name: "show status", args: args{in: StatusInDefault}, want: ShowStatus{
+			Version:             "2.15.1",
+			RouterId:            net.IP{192, 168, 32, 79}.To16(),
+			Hostname:            "infra2",
+			ServerTime:          parseTime("2024-10-13 14:39:40.531"),
+			LastReboot:          parseTime("2024-10-12 20:41:10.197"),
+			LastReconfiguration: parseTime("2024-10-13 09:25:06.844"),
+			DaemonState:         "Daemon is up and running",
+		}},
+		{name: "graceful restart", args: args{in: StatusInGracefulRestart}, want: ShowStatus{
+			Version:             "2.15.1",
+			RouterId:            net.IP{192, 168, 32, 79}.To16(),
+			Hostname:            "infra2",
+			ServerTime:          parseTime("2024-10-13 14:39:40.531"),
+			LastReboot:          parseTime("2024-10-13 14:39:20.002"),
+			LastReconfiguration: parseTime("2024-10-13 14:39:20.002"),
+			DaemonState:         "Daemon is up and running",
+			GracefulRestart:     true,
+		}},
+		{name: "shutdown", args: args{in: StatusInShutdown}, want: ShowStatus{
+			Version:             "2.15.1",
+			RouterId:            net.IP{192, 168, 32, 79}.To16(),
+			Hostname:            "infra2",
+			ServerTime:          parseTime("2024-10-13 14:39:40.531"),
+			LastReboot:          parseTime("2024-10-12 20:41:10.197"),
+			LastReconfiguration: parseTime("2024-10-13 09:25:06.844"),
+			DaemonState:         "Shutdown in progress",
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -139,6 +195,43 @@ xxx_gw1    BGP        ---        start  2024-10-13 09:25:06  Active        Socke
 
 `
 
+var showProtocolsAllIPv6 = `
+BIRD 2.15.1 ready.
+Name       Proto      Table      State  Since         Info
+ll_gw1     BGP        ---        up     2024-10-12 20:41:14  Established
+  BGP state:          Established
+    Neighbor address: fe80::1%eth0
+    Neighbor AS:      4200000001
+    Local AS:         64842
+    Neighbor ID:      192.168.131.1
+  Channel ipv6
+    State:          UP
+    Table:          master6
+    Preference:     100
+    Input filter:   (unnamed)
+    Output filter:  (unnamed)
+    Import limit:   1000 [HIT]
+      Action:       block
+    Routes:         3 imported, 1 filtered, 5 exported, 3 preferred
+    BGP Next hop:   2001:db8::2 fe80::2
+  Channel flow6
+    State:          UP
+    Table:          flowtab6
+    Preference:     100
+    Input filter:   ACCEPT
+    Output filter:  REJECT
+    Routes:         0 imported, 0 exported, 0 preferred
+
+v6_gw1     BGP        ---        up     2024-10-12 20:41:14  Established
+  BGP state:          Established
+    Neighbor address: 2001:db8::1
+    Neighbor port:    1179
+    Neighbor AS:      64844
+    Local AS:         64842
+    Neighbor ID:      192.168.131.2
+
+`
+
 func mustParseTime(t time.Time, err error) time.Time {
 	if err != nil {
 		panic(err)
@@ -146,6 +239,42 @@ func mustParseTime(t time.Time, err error) time.Time {
 	return t
 }
 
+func TestParseShowProtocols(t *testing.T) {
+	since := mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-12 20:41:10", time.Local))
+	tests := []struct {
+		name string
+		in   string
+		want []ProtocolStatus
+	}{
+		{name: "show protocols all", in: showProtocolsAllDefault, want: []ProtocolStatus{
+			{Name: "helpers", Proto: "Static", Table: "master4", State: "up", Since: since},
+			{Name: "device1", Proto: "Device", State: "up", Since: since},
+			{Name: "direct1", Proto: "Direct", State: "up", Since: since},
+			{
+				Name:  "ber1_gw1",
+				Proto: "BGP",
+				State: "up",
+				Since: mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-12 20:41:14", time.Local)),
+				Info:  "Established",
+			},
+			{
+				Name:  "xxx_gw1",
+				Proto: "BGP",
+				State: "start",
+				Since: mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-13 09:25:06", time.Local)),
+				Info:  "Active Socket: No route to host",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseShowProtocols(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseShowProtocols() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseShowProtocolsAll(t *testing.T) {
 	type args struct {
 		in string
@@ -160,24 +289,125 @@ func TestParseShowProtocolsAll(t *testing.T) {
 				Name:            "ber1_gw1",
 				Table:           "",
 				Up:              true,
+				ProtoState:      "up",
 				State:           "Established",
-				Since:           mustParseTime(time.Parse(time.DateTime, "2024-10-12 20:41:14")),
+				Since:           mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-12 20:41:14", time.Local)),
 				NeighborAddress: net.IPv4(192, 168, 32, 1),
+				NeighborAs:      64846,
+				NeighborId:      net.IPv4(192, 168, 32, 1),
 				LocalAs:         64846,
+				Session:         "internal multihop AS4",
+				SourceAddress:   net.IPv4(192, 168, 32, 79),
+				HoldTime:        15,
+				KeepaliveTime:   5,
 				Channels: map[string]ProtocolBGPChannel{
-					"ipv4": {Name: "ipv4", Imported: 21, Exported: 0, Preferred: 21},
+					"ipv4": {
+						Name:         "ipv4",
+						Afi:          1,
+						Safi:         1,
+						State:        "UP",
+						Table:        "master4",
+						Preference:   100,
+						InputFilter:  "(unnamed)",
+						OutputFilter: "(unnamed)",
+						Imported:     21,
+						Exported:     0,
+						Preferred:    21,
+						BGPNextHop:   []net.IP{net.IPv4(192, 168, 32, 79)},
+						ImportUpdates: RouteChangeStats{
+							"received": 1674, "rejected": 0, "filtered": 0, "ignored": 18, "accepted": 1656,
+						},
+						ImportWithdraws: RouteChangeStats{
+							"received": 459, "rejected": 0, "ignored": 0, "accepted": 459,
+						},
+						ExportUpdates: RouteChangeStats{
+							"received": 1658, "rejected": 1656, "filtered": 2, "accepted": 0,
+						},
+						ExportWithdraws: RouteChangeStats{
+							"received": 459, "accepted": 0,
+						},
+					},
 				},
 			},
 			{
 				Name:            "xxx_gw1",
 				Table:           "",
 				Up:              false,
+				ProtoState:      "start",
 				State:           "Active",
-				Since:           mustParseTime(time.Parse(time.DateTime, "2024-10-13 09:25:06")),
+				Since:           mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-13 09:25:06", time.Local)),
 				NeighborAddress: net.IPv4(192, 168, 32, 253),
+				NeighborAs:      64846,
 				LocalAs:         64846,
+				LastError:       "Socket: No route to host",
+				Channels: map[string]ProtocolBGPChannel{
+					"ipv4": {
+						Name:         "ipv4",
+						Afi:          1,
+						Safi:         1,
+						State:        "DOWN",
+						Table:        "master4",
+						Preference:   100,
+						InputFilter:  "(unnamed)",
+						OutputFilter: "(unnamed)",
+					},
+				},
+			},
+		}},
+		{name: "show protocols all ipv6", args: args{in: showProtocolsAllIPv6}, want: []ProtocolBGPStatus{
+			{
+				Name:            "v6_gw1",
+				Up:              true,
+				ProtoState:      "up",
+				State:           "Established",
+				Since:           mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-12 20:41:14", time.Local)),
+				NeighborAddress: net.ParseIP("2001:db8::1"),
+				NeighborPort:    1179,
+				NeighborAs:      64844,
+				NeighborId:      net.IPv4(192, 168, 131, 2),
+				LocalAs:         64842,
 				Channels:        map[string]ProtocolBGPChannel{},
 			},
+			{
+				Name:              "ll_gw1",
+				Up:                true,
+				ProtoState:        "up",
+				State:             "Established",
+				Since:             mustParseTime(time.ParseInLocation(time.DateTime, "2024-10-12 20:41:14", time.Local)),
+				NeighborAddress:   net.ParseIP("fe80::1"),
+				NeighborInterface: "eth0",
+				NeighborAs:        4200000001,
+				NeighborId:        net.IPv4(192, 168, 131, 1),
+				LocalAs:           64842,
+				Channels: map[string]ProtocolBGPChannel{
+					"ipv6": {
+						Name:         "ipv6",
+						Afi:          2,
+						Safi:         1,
+						State:        "UP",
+						Table:        "master6",
+						Preference:   100,
+						InputFilter:  "(unnamed)",
+						OutputFilter: "(unnamed)",
+						Imported:     3,
+						Filtered:     1,
+						Exported:     5,
+						Preferred:    3,
+						ImportLimit:  1000,
+						BGPNextHop:   []net.IP{net.ParseIP("2001:db8::2"), net.ParseIP("fe80::2")},
+					},
+					"flow6": {
+						Name:         "flow6",
+						Afi:          2,
+						Safi:         133,
+						State:        "UP",
+						Table:        "flowtab6",
+						Preference:   100,
+						InputFilter:  "ACCEPT",
+						OutputFilter: "REJECT",
+					},
+				},
+			},
 		}},
 	}
 	for _, tt := range tests {
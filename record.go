@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordCmd captures bird's replies to the commands of a refresh, to
+// reproduce what bird2snmp makes of them with fake-bird.
+type RecordCmd struct {
+	Output   string   `arg:"" help:"file to write the recording to"`
+	Commands []string `short:"c" name:"command" sep:"none" help:"command to record instead of those of a refresh, repeatable"`
+}
+
+func (c *RecordCmd) Run(ctx context.Context) error {
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# bird2snmp recording of %s at %s\n", CLI.BirdSock, time.Now().Format(time.RFC3339))
+
+	client, err := DialBirdRecording(ctx, CLI.BirdSock, w)
+	if err != nil {
+		os.Remove(c.Output)
+		return err
+	}
+	defer client.Close()
+	client.Timeout = CLI.BirdTimeout
+
+	record := func(command string) (string, error) {
+		out, err := client.Command(ctx, command)
+		var replyErr *BirdReplyError
+		if errors.As(err, &replyErr) {
+			// recorded as well, the connection stays usable
+			log.Printf("[WARN] %s: %v", command, err)
+			return out, nil
+		}
+		return out, err
+	}
+
+	if len(c.Commands) > 0 {
+		for _, command := range c.Commands {
+			if _, err := record(command); err != nil {
+				return err
+			}
+		}
+		return c.close(w, f)
+	}
+
+	// full and incremental refreshes
+	for _, command := range []string{"show status", "show protocols all"} {
+		if _, err := record(command); err != nil {
+			return err
+		}
+	}
+	summary, err := record("show protocols")
+	if err != nil {
+		return err
+	}
+	for _, proto := range ParseShowProtocols(summary) {
+		if _, err := record("show protocols all " + proto.Name); err != nil {
+			return err
+		}
+	}
+	return c.close(w, f)
+}
+
+func (c *RecordCmd) close(w *bufio.Writer, f *os.File) error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Recorded bird's replies to %s", c.Output)
+	return nil
+}
+
+// birdRecorder writes the exchanges of all connections of a running agent
+// to a single recording. The refresh connection is recorded as fake-bird
+// replays it, with the time of every command, the banners of reconnects
+// and commands left without a reply, e.g. when timed out, as comments. Log
+// connections are recorded as comments only, fake-bird can't replay
+// asynchronous messages.
+type birdRecorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	banner  bool
+	pending string // command of the refresh connection waiting for a reply
+}
+
+func newBirdRecorder(w io.Writer, path string) *birdRecorder {
+	fmt.Fprintf(w, "# bird2snmp recording of %s at %s\n", path, time.Now().Format(time.RFC3339))
+	return &birdRecorder{w: w}
+}
+
+// transcript returns the transcript of the refresh connection, or of log
+// connections if echo is set.
+func (r *birdRecorder) transcript(echo bool) io.Writer {
+	return birdRecorderTranscript{recorder: r, echo: echo}
+}
+
+// birdRecorderTranscript is a transcript writer of a birdRecorder, bird
+// clients write it a line at a time.
+type birdRecorderTranscript struct {
+	recorder *birdRecorder
+	echo     bool
+}
+
+func (t birdRecorderTranscript) Write(p []byte) (int, error) {
+	r := t.recorder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := string(p)
+	now := time.Now().Format(time.RFC3339Nano)
+	switch {
+	case t.echo:
+		line = fmt.Sprintf("# %s log %s", now, line)
+	case strings.HasPrefix(line, birdRecordingCommand):
+		// written with the first line of its reply
+		line, r.pending = r.unanswered()+fmt.Sprintf("# %s\n", now), line
+	case strings.HasPrefix(line, fmt.Sprintf("%04d ", birdCodeReady)):
+		if r.banner {
+			line = r.unanswered() + fmt.Sprintf("# %s reconnected %s", now, line)
+		}
+		r.banner = true
+	default:
+		line, r.pending = r.pending+line, ""
+	}
+	if _, err := io.WriteString(r.w, line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// unanswered returns the pending command as a comment, it got no reply.
+func (r *birdRecorder) unanswered() string {
+	if r.pending == "" {
+		return ""
+	}
+	line := "# no reply " + r.pending
+	r.pending = ""
+	return line
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBirdRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	bird := startFakeBird(t, path, fakeBirdReplies)
+	defer bird.Close()
+
+	var out strings.Builder
+	recorder := newBirdRecorder(&out, path)
+	command := func(echo bool, command string) {
+		t.Helper()
+		c, err := DialBirdRecording(context.Background(), path, recorder.transcript(echo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := c.Command(context.Background(), command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	command(false, "show status")
+	command(true, "show protocols")
+	// a command timing out, then a reconnect of the refresh connection
+	io.WriteString(recorder.transcript(false), "> show route\n")
+	command(false, "show status")
+	// cancelled when the agent stops
+	io.WriteString(recorder.transcript(false), "> show protocols all\n")
+
+	for i, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if strings.Contains(line, "show protocols") && !strings.HasPrefix(line, "# ") {
+			t.Errorf("line %d = %q, want log connections recorded as comments", i+1, line)
+		}
+	}
+	if got := strings.Count(out.String(), " reconnected 0001 "); got != 1 {
+		t.Errorf("recording has %d reconnect comments, want 1:\n%s", got, out.String())
+	}
+	if !strings.Contains(out.String(), "\n# no reply > show route\n") {
+		t.Errorf("recording without the unanswered command:\n%s", out.String())
+	}
+	recording, err := parseBirdRecording(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("parseBirdRecording() error = %v\n%s", err, out.String())
+	}
+	if got := len(recording.replies["show_status"]); got != 2 {
+		t.Errorf("show status recorded %d times, want 2", got)
+	}
+	if _, ok := recording.replies["show_protocols"]; ok {
+		t.Error("command of a log connection replayable")
+	}
+}
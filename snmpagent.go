@@ -0,0 +1,553 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/posteo/go-agentx"
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// SNMP versions and pdu types of RFC 1157 and RFC 3416.
+const (
+	snmpVersion1  = 0
+	snmpVersion2c = 1
+	snmpVersion3  = 3
+
+	snmpGetRequest     = 0xa0
+	snmpGetNextRequest = 0xa1
+	snmpResponse       = 0xa2
+	snmpSetRequest     = 0xa3
+	snmpGetBulkRequest = 0xa5
+	snmpReport         = 0xa8
+)
+
+// SNMP error statuses.
+const (
+	snmpNoError     = 0
+	snmpTooBig      = 1
+	snmpNoSuchName  = 2
+	snmpGenErr      = 5
+	snmpNotWritable = 17
+
+	snmpAuthorizationError = 16
+)
+
+// snmpMaxMessageSize is the largest response sent, the largest UDP payload.
+const snmpMaxMessageSize = 65507
+
+// snmpMessageOverhead bounds the size of an SNMPv1/v2c response without its
+// variable bindings and community.
+const snmpMessageOverhead = 64
+
+var (
+	oidSystem      = value.OID{1, 3, 6, 1, 2, 1, 1}
+	oidSysDescr    = value.OID{1, 3, 6, 1, 2, 1, 1, 1, 0}
+	oidSysObjectID = value.OID{1, 3, 6, 1, 2, 1, 1, 2, 0}
+	oidSysName     = value.OID{1, 3, 6, 1, 2, 1, 1, 5, 0}
+)
+
+var errSnmpTooBig = errors.New("response too big")
+
+// snmpView is a subtree served by a handler.
+type snmpView struct {
+	subtree value.OID
+	end     value.OID // first oid following the subtree
+	handler agentx.Handler
+}
+
+// snmpViews are the views a request may read, sorted by subtree.
+type snmpViews []snmpView
+
+// pin returns views answering from the state of their handlers now, for
+// the bindings and repetitions of a request to be consistent. A handler
+// serving several views is pinned once for all of them.
+func (views snmpViews) pin() snmpViews {
+	pinned := make(snmpViews, len(views))
+	handlers := map[agentx.Handler]agentx.Handler{}
+	for i, view := range views {
+		pinned[i] = view
+		pinner, ok := view.handler.(agentx.Pinner)
+		if !ok {
+			continue
+		}
+		if _, ok := handlers[view.handler]; !ok {
+			handlers[view.handler] = pinner.Pin()
+		}
+		pinned[i].handler = handlers[view.handler]
+	}
+	return pinned
+}
+
+// snmpVarBind is a variable binding of a request or response.
+type snmpVarBind struct {
+	oid     value.OID
+	varType pdu.VariableType
+	value   interface{}
+}
+
+// snmpMessage is a decoded SNMPv1 or SNMPv2c request.
+type snmpMessage struct {
+	version   int64
+	community string
+	pduType   byte
+	requestID int64
+	// the error status and index fields, non-repeaters and max-repetitions
+	// of GetBulk requests
+	nonRepeaters   int64
+	maxRepetitions int64
+	varBinds       []value.OID
+
+	limit int       // largest size of the variable bindings of a response
+	views snmpViews // what the requester may read
+	usm   *usmMessage
+}
+
+// SnmpAgent answers SNMP requests on UDP without an AgentX master, for
+// hosts not running snmpd. It is read-only, SNMPv1/v2c requests with
+// another community than the configured one are dropped, SNMPv3 requests
+// are answered once EnableUSM is called.
+type SnmpAgent struct {
+	conn      net.PacketConn
+	community string // empty disables SNMPv1/v2c
+	views     snmpViews
+	usm       *usmEngine
+}
+
+// NewSnmpAgent listens on the UDP address addr and serves the subtrees of
+// handler along with the system group. An empty community disables SNMPv1
+// and SNMPv2c.
+func NewSnmpAgent(addr string, community string, handler agentx.Handler, subtrees []value.OID) (*SnmpAgent, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	a := &SnmpAgent{conn: conn, community: community}
+	a.addView(oidSystem, newSystemGroup(time.Now()))
+	for _, subtree := range subtrees {
+		a.addView(subtree, handler)
+	}
+	sort.Slice(a.views, func(i int, j int) bool {
+		return compareOids(a.views[i].subtree, a.views[j].subtree) == -1
+	})
+	return a, nil
+}
+
+func (a *SnmpAgent) addView(subtree value.OID, handler agentx.Handler) {
+	end := append(value.OID{}, subtree...)
+	end[len(end)-1]++
+	a.views = append(a.views, snmpView{subtree: subtree, end: end, handler: handler})
+}
+
+// newSystemGroup returns the scalars of the SNMPv2-MIB system group an
+// agent is expected to serve.
+func newSystemGroup(started time.Time) *ListHandler {
+	data := &ListHandler{}
+	item := data.Add(oidSysDescr)
+	item.Type = pdu.VariableTypeOctetString
+	item.Value = "bird2snmp"
+
+	item = data.Add(oidSysObjectID)
+	item.Type = pdu.VariableTypeObjectIdentifier
+	item.Value = oidBird.String()
+
+	item = data.Add(oidSysUpTime)
+	item.Type = pdu.VariableTypeTimeTicks
+	item.Value = func() interface{} {
+		return time.Since(started)
+	}
+
+	hostname, _ := os.Hostname()
+	item = data.Add(oidSysName)
+	item.Type = pdu.VariableTypeOctetString
+	item.Value = hostname
+	return data
+}
+
+// Addr returns the address the agent listens on.
+func (a *SnmpAgent) Addr() net.Addr {
+	return a.conn.LocalAddr()
+}
+
+// Serve answers requests until ctx is cancelled.
+func (a *SnmpAgent) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		a.conn.Close()
+	})
+	defer stop()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		response := a.handle(buf[:n])
+		if response == nil {
+			continue
+		}
+		if _, err := a.conn.WriteTo(response, addr); err != nil {
+			log.Printf("[WARN] Failed to send SNMP response to %s: %v", addr, err)
+		}
+	}
+}
+
+// Close stops listening.
+func (a *SnmpAgent) Close() error {
+	return a.conn.Close()
+}
+
+// handle returns the response to a request, nil if it is dropped.
+func (a *SnmpAgent) handle(packet []byte) []byte {
+	var msg *snmpMessage
+	version, err := snmpMessageVersion(packet)
+	switch {
+	case err != nil:
+		return nil
+	case version == snmpVersion3:
+		if a.usm == nil {
+			return nil
+		}
+		var reply []byte
+		if msg, reply = a.usm.parse(packet); msg == nil {
+			return reply
+		}
+	default:
+		msg, err = parseSnmpMessage(packet)
+		if err != nil || a.community == "" || msg.community != a.community {
+			return nil
+		}
+		msg.views = a.views
+	}
+	if msg.version == snmpVersion1 && msg.pduType == snmpGetBulkRequest {
+		return nil
+	}
+	msg.views = msg.views.pin()
+
+	var varBinds []snmpVarBind
+	switch msg.pduType {
+	case snmpGetRequest:
+		varBinds, err = a.getRequest(msg)
+	case snmpGetNextRequest:
+		varBinds, err = a.getNextRequest(msg)
+	case snmpGetBulkRequest:
+		varBinds, err = a.getBulkRequest(msg)
+	case snmpSetRequest:
+		if len(msg.varBinds) == 0 {
+			break
+		}
+		if msg.version == snmpVersion1 {
+			return msg.errorResponse(snmpNoSuchName, 1)
+		}
+		return msg.errorResponse(snmpNotWritable, 1)
+	default:
+		return nil
+	}
+
+	var indexErr *snmpIndexError
+	switch {
+	case errors.As(err, &indexErr):
+		return msg.errorResponse(indexErr.status, indexErr.index)
+	case errors.Is(err, errSnmpTooBig):
+		return msg.response(snmpTooBig, 0, nil)
+	}
+	return msg.response(snmpNoError, 0, varBinds)
+}
+
+// snmpIndexError is a failure of the variable binding at index, starting
+// from 1.
+type snmpIndexError struct {
+	status int
+	index  int
+}
+
+func (e *snmpIndexError) Error() string {
+	return fmt.Sprintf("error status %d at index %d", e.status, e.index)
+}
+
+// get answers a Get for oid from the view containing it.
+func (views snmpViews) get(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
+	for _, view := range views {
+		if oidHasPrefix(oid, view.subtree) {
+			return view.handler.Get(oid)
+		}
+	}
+	return nil, pdu.VariableTypeNoSuchObject, nil, nil
+}
+
+// getNext answers a GetNext for oid from the views following it, a nil oid
+// is the end of the mib view.
+func (views snmpViews) getNext(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
+	for _, view := range views {
+		if compareOids(view.end, oid) != 1 {
+			continue
+		}
+		from, includeFrom := oid, false
+		if compareOids(oid, view.subtree) == -1 {
+			from, includeFrom = view.subtree, true
+		}
+		next, t, v, err := view.handler.GetNext(from, includeFrom, view.end)
+		if err != nil || next != nil {
+			return next, t, v, err
+		}
+	}
+	return nil, pdu.VariableTypeEndOfMIBView, nil, nil
+}
+
+// getNextV1 is getNext skipping Counter64 values, which SNMPv1 can't
+// carry.
+func (views snmpViews) getNextV1(oid value.OID) (value.OID, pdu.VariableType, interface{}, error) {
+	for {
+		next, t, v, err := views.getNext(oid)
+		if err != nil || next == nil || t != pdu.VariableTypeCounter64 {
+			return next, t, v, err
+		}
+		oid = next
+	}
+}
+
+func (a *SnmpAgent) getRequest(msg *snmpMessage) ([]snmpVarBind, error) {
+	varBinds := make([]snmpVarBind, 0, len(msg.varBinds))
+	for i, oid := range msg.varBinds {
+		got, t, v, err := msg.views.get(oid)
+		if err != nil {
+			return nil, &snmpIndexError{status: snmpGenErr, index: i + 1}
+		}
+		if got == nil {
+			got = oid
+		}
+		if msg.version == snmpVersion1 {
+			switch t {
+			case pdu.VariableTypeNoSuchObject, pdu.VariableTypeNoSuchInstance, pdu.VariableTypeCounter64:
+				return nil, &snmpIndexError{status: snmpNoSuchName, index: i + 1}
+			}
+		}
+		varBinds = append(varBinds, snmpVarBind{oid: got, varType: t, value: v})
+	}
+	return varBinds, nil
+}
+
+func (a *SnmpAgent) getNextRequest(msg *snmpMessage) ([]snmpVarBind, error) {
+	varBinds := make([]snmpVarBind, 0, len(msg.varBinds))
+	for i, oid := range msg.varBinds {
+		varBind, err := a.next(msg, oid)
+		if err != nil {
+			return nil, &snmpIndexError{status: snmpGenErr, index: i + 1}
+		}
+		if varBind.varType == pdu.VariableTypeEndOfMIBView && msg.version == snmpVersion1 {
+			return nil, &snmpIndexError{status: snmpNoSuchName, index: i + 1}
+		}
+		varBinds = append(varBinds, varBind)
+	}
+	return varBinds, nil
+}
+
+// next returns the variable binding following oid, endOfMibView after
+// the last one.
+func (a *SnmpAgent) next(msg *snmpMessage, oid value.OID) (snmpVarBind, error) {
+	getNext := msg.views.getNext
+	if msg.version == snmpVersion1 {
+		getNext = msg.views.getNextV1
+	}
+	next, t, v, err := getNext(oid)
+	if err != nil {
+		return snmpVarBind{}, err
+	}
+	if next == nil {
+		return snmpVarBind{oid: oid, varType: pdu.VariableTypeEndOfMIBView}, nil
+	}
+	return snmpVarBind{oid: next, varType: t, value: v}, nil
+}
+
+// getBulkRequest answers the non-repeaters once and the repeaters up to
+// max-repetitions times, as many as fit into a response.
+func (a *SnmpAgent) getBulkRequest(msg *snmpMessage) ([]snmpVarBind, error) {
+	nonRepeaters := int(min(max(msg.nonRepeaters, 0), int64(len(msg.varBinds))))
+	maxRepetitions := max(msg.maxRepetitions, 0)
+
+	varBinds := []snmpVarBind{}
+	size := 0
+	add := func(varBind snmpVarBind) bool {
+		b, err := berVarBind(varBind.oid, varBind.varType, varBind.value)
+		if err != nil {
+			log.Printf("[ERROR] Failed to encode %s: %v", varBind.oid, err)
+			b, _ = berVarBind(varBind.oid, pdu.VariableTypeNull, nil)
+		}
+		if size+len(b) > msg.limit {
+			return false
+		}
+		size += len(b)
+		varBinds = append(varBinds, varBind)
+		return true
+	}
+
+	for i, oid := range msg.varBinds[:nonRepeaters] {
+		varBind, err := a.next(msg, oid)
+		if err != nil {
+			return nil, &snmpIndexError{status: snmpGenErr, index: i + 1}
+		}
+		if !add(varBind) {
+			return nil, errSnmpTooBig
+		}
+	}
+
+	repeaters := append([]value.OID{}, msg.varBinds[nonRepeaters:]...)
+	for r := int64(0); r < maxRepetitions && len(repeaters) > 0; r++ {
+		ended := 0
+		for j, oid := range repeaters {
+			varBind, err := a.next(msg, oid)
+			if err != nil {
+				return nil, &snmpIndexError{status: snmpGenErr, index: nonRepeaters + j + 1}
+			}
+			if varBind.varType == pdu.VariableTypeEndOfMIBView {
+				ended++
+			}
+			if !add(varBind) {
+				return varBinds, nil
+			}
+			repeaters[j] = varBind.oid
+		}
+		// every repeater reached the end of the mib view
+		if ended == len(repeaters) {
+			break
+		}
+	}
+	return varBinds, nil
+}
+
+// snmpMessageVersion returns the version field of a message.
+func snmpMessageVersion(packet []byte) (int64, error) {
+	content, _, err := berExpect(packet, berSequence)
+	if err != nil {
+		return 0, err
+	}
+	field, _, err := berExpect(content, berInteger)
+	if err != nil {
+		return 0, err
+	}
+	return berParseInt(field)
+}
+
+// parseSnmpMessage decodes an SNMPv1 or SNMPv2c request.
+func parseSnmpMessage(packet []byte) (*snmpMessage, error) {
+	msg := &snmpMessage{}
+	content, _, err := berExpect(packet, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	field, content, err := berExpect(content, berInteger)
+	if err != nil {
+		return nil, err
+	}
+	if msg.version, err = berParseInt(field); err != nil {
+		return nil, err
+	}
+	if msg.version != snmpVersion1 && msg.version != snmpVersion2c {
+		return nil, fmt.Errorf("unsupported SNMP version %d", msg.version)
+	}
+	field, content, err = berExpect(content, berOctetString)
+	if err != nil {
+		return nil, err
+	}
+	msg.community = string(field)
+	msg.limit = snmpMaxMessageSize - snmpMessageOverhead - len(msg.community)
+	return msg, parseSnmpPDU(msg, content)
+}
+
+// parseSnmpPDU decodes the pdu of a request into msg.
+func parseSnmpPDU(msg *snmpMessage, b []byte) error {
+	var content []byte
+	var err error
+	msg.pduType, content, _, err = berRead(b)
+	if err != nil {
+		return err
+	}
+	for _, v := range []*int64{&msg.requestID, &msg.nonRepeaters, &msg.maxRepetitions} {
+		var field []byte
+		field, content, err = berExpect(content, berInteger)
+		if err != nil {
+			return err
+		}
+		if *v, err = berParseInt(field); err != nil {
+			return err
+		}
+	}
+
+	varBinds, _, err := berExpect(content, berSequence)
+	if err != nil {
+		return err
+	}
+	for len(varBinds) > 0 {
+		var varBind, field []byte
+		varBind, varBinds, err = berExpect(varBinds, berSequence)
+		if err != nil {
+			return err
+		}
+		field, _, err = berExpect(varBind, berOID)
+		if err != nil {
+			return err
+		}
+		oid, err := berParseOID(field)
+		if err != nil {
+			return err
+		}
+		msg.varBinds = append(msg.varBinds, oid)
+	}
+	return nil
+}
+
+// encodeSnmpPDU encodes a pdu with encoded variable bindings.
+func encodeSnmpPDU(pduType byte, requestID int64, status int, index int, varBinds []byte) []byte {
+	b := berTLV(berInteger, berInt(requestID))
+	b = append(b, berTLV(berInteger, berInt(int64(status)))...)
+	b = append(b, berTLV(berInteger, berInt(int64(index)))...)
+	b = append(b, berTLV(berSequence, varBinds)...)
+	return berTLV(pduType, b)
+}
+
+// response encodes a response with the request id and community or
+// security parameters of msg. Variable bindings failing to encode turn it
+// into a genErr response, a response exceeding the size limit of msg into
+// a tooBig one.
+func (msg *snmpMessage) response(status int, index int, varBinds []snmpVarBind) []byte {
+	var encoded []byte
+	for i, varBind := range varBinds {
+		b, err := berVarBind(varBind.oid, varBind.varType, varBind.value)
+		if err != nil {
+			log.Printf("[ERROR] Failed to encode %s: %v", varBind.oid, err)
+			return msg.errorResponse(snmpGenErr, i+1)
+		}
+		encoded = append(encoded, b...)
+	}
+	if len(encoded) > msg.limit && status != snmpTooBig {
+		return msg.response(snmpTooBig, 0, nil)
+	}
+
+	b := encodeSnmpPDU(snmpResponse, msg.requestID, status, index, encoded)
+	if msg.usm != nil {
+		return msg.usm.encode(b)
+	}
+	header := berTLV(berInteger, berInt(msg.version))
+	header = append(header, berTLV(berOctetString, []byte(msg.community))...)
+	return berTLV(berSequence, append(header, b...))
+}
+
+// errorResponse encodes an error response, the variable bindings are the
+// ones of the request.
+func (msg *snmpMessage) errorResponse(status int, index int) []byte {
+	varBinds := make([]snmpVarBind, 0, len(msg.varBinds))
+	for _, oid := range msg.varBinds {
+		varBinds = append(varBinds, snmpVarBind{oid: oid, varType: pdu.VariableTypeNull})
+	}
+	return msg.response(status, index, varBinds)
+}
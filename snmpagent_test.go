@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_berParseOID(t *testing.T) {
+	tests := []struct {
+		name string
+		oid  value.OID
+	}{
+		{name: "bgp", oid: oidBgpPeerState},
+		{name: "large sub-identifiers", oid: value.OID{1, 3, 6, 1, 4, 1, 2636, 4294967295}},
+		{name: "joint-iso-itu-t", oid: value.OID{2, 999, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := berParseOID(berOIDContent(tt.oid))
+			if err != nil || !reflect.DeepEqual(got, tt.oid) {
+				t.Errorf("berParseOID() = %v, %v, want %v", got, err, tt.oid)
+			}
+		})
+	}
+	if _, err := berParseOID([]byte{0x2b, 0x86}); err == nil {
+		t.Error("berParseOID() succeeded with a truncated sub-identifier")
+	}
+}
+
+func Test_berParseInt(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 2147483647, -2147483648} {
+		got, err := berParseInt(berInt(v))
+		if err != nil || got != v {
+			t.Errorf("berParseInt(berInt(%d)) = %d, %v", v, got, err)
+		}
+	}
+	if got := berUint(0xffffffff); !reflect.DeepEqual(got, []byte{0, 0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("berUint() = %x, want a leading zero byte", got)
+	}
+}
+
+// snmpTestRequest encodes a request with null values.
+func snmpTestRequest(version int64, community string, pduType byte, a int64, b int64, oids ...value.OID) []byte {
+	var varBinds []byte
+	for _, oid := range oids {
+		varBind, _ := berVarBind(oid, pdu.VariableTypeNull, nil)
+		varBinds = append(varBinds, varBind...)
+	}
+	body := berTLV(berInteger, berInt(42))
+	body = append(body, berTLV(berInteger, berInt(a))...)
+	body = append(body, berTLV(berInteger, berInt(b))...)
+	body = append(body, berTLV(berSequence, varBinds)...)
+	msg := berTLV(berInteger, berInt(version))
+	msg = append(msg, berTLV(berOctetString, []byte(community))...)
+	msg = append(msg, berTLV(pduType, body)...)
+	return berTLV(berSequence, msg)
+}
+
+// snmpTestResponse is a decoded response, values are kept encoded.
+type snmpTestResponse struct {
+	status   int64
+	index    int64
+	varBinds []snmpTestVarBind
+}
+
+type snmpTestVarBind struct {
+	oid   value.OID
+	tag   byte
+	value []byte
+}
+
+func parseSnmpTestResponse(t *testing.T, b []byte) snmpTestResponse {
+	t.Helper()
+	must := func(content []byte, rest []byte, err error) ([]byte, []byte) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("malformed response: %v", err)
+		}
+		return content, rest
+	}
+	content, _ := must(berExpect(b, berSequence))
+	_, content = must(berExpect(content, berInteger))
+	_, content = must(berExpect(content, berOctetString))
+	pduType, response := parseSnmpTestPDU(t, content)
+	if pduType != snmpResponse {
+		t.Fatalf("pdu type = 0x%02x, want a response", pduType)
+	}
+	return response
+}
+
+// parseSnmpTestPDU decodes a response or report pdu.
+func parseSnmpTestPDU(t *testing.T, b []byte) (byte, snmpTestResponse) {
+	t.Helper()
+	must := func(content []byte, rest []byte, err error) ([]byte, []byte) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("malformed response: %v", err)
+		}
+		return content, rest
+	}
+	pduType, content, _, err := berRead(b)
+	if err != nil {
+		t.Fatalf("malformed response: %v", err)
+	}
+	var fields [3]int64
+	for i := range fields {
+		var field []byte
+		field, content = must(berExpect(content, berInteger))
+		fields[i], _ = berParseInt(field)
+	}
+	// reports on encrypted requests can't know the request id
+	if fields[0] != 42 && (pduType != snmpReport || fields[0] != 0) {
+		t.Fatalf("request id = %d, want 42", fields[0])
+	}
+	response := snmpTestResponse{status: fields[1], index: fields[2]}
+	varBinds, _ := must(berExpect(content, berSequence))
+	for len(varBinds) > 0 {
+		var varBind, field []byte
+		varBind, varBinds = must(berExpect(varBinds, berSequence))
+		field, varBind = must(berExpect(varBind, berOID))
+		oid, err := berParseOID(field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag, value, _, err := berRead(varBind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.varBinds = append(response.varBinds, snmpTestVarBind{oid: oid, tag: tag, value: value})
+	}
+	return pduType, response
+}
+
+// snmpTestExchange sends a request and returns the response, nil if there
+// is none.
+func snmpTestExchange(t *testing.T, conn net.Conn, request []byte) []byte {
+	t.Helper()
+	if _, err := conn.Write(request); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil
+	}
+	return buf[:n]
+}
+
+func TestSnmpAgent(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	agent, err := NewSnmpAgent("127.0.0.1:0", "secret", h, DefaultSubtrees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.Serve(ctx)
+
+	conn, err := net.Dial("udp", agent.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	exchange := func(t *testing.T, request []byte) (snmpTestResponse, bool) {
+		t.Helper()
+		response := snmpTestExchange(t, conn, request)
+		if response == nil {
+			return snmpTestResponse{}, false
+		}
+		return parseSnmpTestResponse(t, response), true
+	}
+
+	localAs := append(append(value.OID{}, oidBgpLocalAs...), 0)
+	unknown := value.OID{1, 3, 6, 1, 2, 1, 15, 99, 0}
+	afterAll := value.OID{1, 3, 6, 1, 9}
+	tests := []struct {
+		name       string
+		request    []byte
+		wantStatus int64
+		wantIndex  int64
+		wantOids   []value.OID
+		wantTags   []byte
+	}{
+		{
+			name:     "get",
+			request:  snmpTestRequest(snmpVersion2c, "secret", snmpGetRequest, 0, 0, oidSysDescr, localAs, unknown),
+			wantOids: []value.OID{oidSysDescr, localAs, unknown},
+			wantTags: []byte{berOctetString, berInteger, byte(pdu.VariableTypeNoSuchObject)},
+		},
+		{
+			name:       "v1 get of a missing object",
+			request:    snmpTestRequest(snmpVersion1, "secret", snmpGetRequest, 0, 0, localAs, unknown),
+			wantStatus: snmpNoSuchName,
+			wantIndex:  2,
+			wantOids:   []value.OID{localAs, unknown},
+			wantTags:   []byte{berNull, berNull},
+		},
+		{
+			name:     "getnext across subtrees",
+			request:  snmpTestRequest(snmpVersion2c, "secret", snmpGetNextRequest, 0, 0, oidSysName, afterAll),
+			wantOids: []value.OID{oidBgpVersion, afterAll},
+			wantTags: []byte{berOctetString, byte(pdu.VariableTypeEndOfMIBView)},
+		},
+		{
+			name:       "v1 getnext after the last object",
+			request:    snmpTestRequest(snmpVersion1, "secret", snmpGetNextRequest, 0, 0, afterAll),
+			wantStatus: snmpNoSuchName,
+			wantIndex:  1,
+			wantOids:   []value.OID{afterAll},
+			wantTags:   []byte{berNull},
+		},
+		{
+			name:     "getbulk",
+			request:  snmpTestRequest(snmpVersion2c, "secret", snmpGetBulkRequest, 1, 3, oidSystem, oidSysName),
+			wantOids: []value.OID{oidSysDescr, oidBgpVersion, localAs, append(append(value.OID{}, oidBgpPeerIdentifier...), 192, 168, 32, 1)},
+			wantTags: []byte{berOctetString, berOctetString, berInteger, byte(pdu.VariableTypeIPAddress)},
+		},
+		{
+			name:       "set",
+			request:    snmpTestRequest(snmpVersion2c, "secret", snmpSetRequest, 0, 0, localAs),
+			wantStatus: snmpNotWritable,
+			wantIndex:  1,
+			wantOids:   []value.OID{localAs},
+			wantTags:   []byte{berNull},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := exchange(t, tt.request)
+			if !ok {
+				t.Fatal("no response")
+			}
+			if got.status != tt.wantStatus || got.index != tt.wantIndex {
+				t.Errorf("error status = %d/%d, want %d/%d", got.status, got.index, tt.wantStatus, tt.wantIndex)
+			}
+			var gotOids []value.OID
+			var gotTags []byte
+			for _, varBind := range got.varBinds {
+				gotOids = append(gotOids, varBind.oid)
+				gotTags = append(gotTags, varBind.tag)
+			}
+			if !reflect.DeepEqual(gotOids, tt.wantOids) || !reflect.DeepEqual(gotTags, tt.wantTags) {
+				t.Errorf("variable bindings = %v %x, want %v %x", gotOids, gotTags, tt.wantOids, tt.wantTags)
+			}
+		})
+	}
+
+	t.Run("wrong community", func(t *testing.T) {
+		if _, ok := exchange(t, snmpTestRequest(snmpVersion2c, "public", snmpGetRequest, 0, 0, oidSysDescr)); ok {
+			t.Error("answered a request with a wrong community")
+		}
+	})
+
+	t.Run("bulk walk", func(t *testing.T) {
+		// the system group and the objects of the registered subtrees
+		want := 4
+		for _, subtree := range DefaultSubtrees {
+			end := append(value.OID{}, subtree...)
+			end[len(end)-1]++
+			h.current().data.Walk(subtree, false, end, func(value.OID, pdu.VariableType, interface{}) bool {
+				want++
+				return true
+			})
+		}
+		walked := 0
+		from := value.OID{1, 3}
+		for {
+			got, ok := exchange(t, snmpTestRequest(snmpVersion2c, "secret", snmpGetBulkRequest, 0, 50, from))
+			if !ok || got.status != snmpNoError || len(got.varBinds) == 0 {
+				t.Fatalf("response = %+v, %v", got, ok)
+			}
+			for _, varBind := range got.varBinds {
+				if varBind.tag == byte(pdu.VariableTypeEndOfMIBView) {
+					if walked != want {
+						t.Errorf("walked %d objects, want %d", walked, want)
+					}
+					return
+				}
+				if compareOids(varBind.oid, from) != 1 {
+					t.Fatalf("%v follows %v", varBind.oid, from)
+				}
+				from = varBind.oid
+				walked++
+			}
+		}
+	})
+}
+
+func Test_snmpViewsPin(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	a := &SnmpAgent{}
+	a.addView(oidSystem, newSystemGroup(time.Now()))
+	a.addView(oidBgp, h)
+	a.addView(oidBird, h)
+	views := a.views.pin()
+	if views[1].handler != views[2].handler {
+		t.Error("pin() pinned a handler once per view")
+	}
+	if views[0].handler != a.views[0].handler {
+		t.Error("pin() replaced a handler without state")
+	}
+
+	// a refresh publishing another generation during the request
+	status := ParseShowStatus(StatusInDefault)
+	h.rebuild(time.Now(), time.Now(), status, nil, nil)
+	localAs := append(append(value.OID{}, oidBgpLocalAs...), 0)
+	if _, _, v, _ := views.get(localAs); v != int32(64846) {
+		t.Errorf("pinned get() = %v, want 64846", v)
+	}
+	if _, _, v, _ := a.views.get(localAs); v != int32(0) {
+		t.Errorf("get() = %v, want 0", v)
+	}
+}
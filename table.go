@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// tableColumn describes a column of a table with rows of type T, the value
+// function produces the column value for a single row.
+type tableColumn[T any] struct {
+	oid     value.OID
+	varType pdu.VariableType
+	value   func(row T) interface{}
+}
+
+// tableRow is a table row with its instance index.
+type tableRow[T any] struct {
+	index value.OID
+	row   T
+}
+
+// addTable adds the columns of a table to data column by column, rows are
+// sorted by their index so the cells are appended in lexicographic order.
+// Cells with a nil value are left out of the table.
+func addTable[T any](data *ListHandler, columns []tableColumn[T], rows []tableRow[T]) {
+	sort.SliceStable(rows, func(i int, j int) bool {
+		return compareOids(rows[i].index, rows[j].index) == -1
+	})
+	for _, column := range columns {
+		for _, row := range rows {
+			v := column.value(row.row)
+			if v == nil {
+				continue
+			}
+			oid := make(value.OID, 0, len(column.oid)+len(row.index))
+			item := data.Add(append(append(oid, column.oid...), row.index...))
+			item.Type = column.varType
+			item.Value = v
+		}
+	}
+}
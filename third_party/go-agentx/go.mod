@@ -0,0 +1,5 @@
+module github.com/posteo/go-agentx
+
+go 1.13
+
+require github.com/stretchr/testify v1.6.1
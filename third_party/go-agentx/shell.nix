@@ -0,0 +1,7 @@
+{ pkgs ? import <nixpkgs> {} }:
+pkgs.mkShell {
+    name = "dev-environment";
+    buildInputs = [
+        pkgs.net-snmp
+    ];
+}
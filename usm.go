@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// msgFlags bits of SNMPv3 messages.
+const (
+	usmFlagAuth       = 0x01
+	usmFlagPriv       = 0x02
+	usmFlagReportable = 0x04
+)
+
+const (
+	usmSecurityModel  = 3          // msgSecurityModel of USM
+	usmTimeWindow     = 150        // seconds, RFC 3414 section 3.2.7
+	usmMaxEngineBoots = 2147483647 // an engine at this count has to be reconfigured
+	usmMinMessageSize = 484        // smallest msgMaxSize a requester may announce
+)
+
+// usmMessageOverhead bounds the size of an SNMPv3 response without its
+// variable bindings, engine IDs, user and context name.
+const usmMessageOverhead = 160
+
+// usmStats are the usmStats counters of SNMP-USER-BASED-SM-MIB, reported to
+// requesters failing security checks.
+var oidUsmStats = value.OID{1, 3, 6, 1, 6, 3, 15, 1, 1}
+
+const (
+	usmStatsUnsupportedSecLevels = 1
+	usmStatsNotInTimeWindows     = 2
+	usmStatsUnknownUserNames     = 3
+	usmStatsUnknownEngineIDs     = 4
+	usmStatsWrongDigests         = 5
+	usmStatsDecryptionErrors     = 6
+)
+
+// usmAuthProtocol is an HMAC authentication protocol of RFC 3414 and
+// RFC 7860.
+type usmAuthProtocol struct {
+	hash   func() hash.Hash
+	macLen int // length of the truncated HMAC
+}
+
+var usmAuthProtocols = map[string]usmAuthProtocol{
+	"SHA":     {sha1.New, 12},
+	"SHA-224": {sha256.New224, 16},
+	"SHA-256": {sha256.New, 24},
+	"SHA-384": {sha512.New384, 32},
+	"SHA-512": {sha512.New, 48},
+}
+
+// usmPrivKeyLengths are the key lengths of the AES privacy protocol of
+// RFC 3826 and its 192 and 256 bit variants.
+var usmPrivKeyLengths = map[string]int{
+	"AES":     16,
+	"AES-192": 24,
+	"AES-256": 32,
+}
+
+// snmpViewNames are the subtrees users' views can refer to by name.
+var snmpViewNames = map[string]value.OID{
+	"system":  oidSystem,
+	"bgp4":    oidBgp,
+	"bgp4v2":  oidBgp4V2,
+	"bird":    oidBird,
+	"cisco":   oidCiscoBgp4,
+	"juniper": oidJnxBgpM2,
+}
+
+// SnmpV3Config configures the SNMPv3 users of the standalone agent.
+type SnmpV3Config struct {
+	// EngineID is the hex encoded snmpEngineID, if empty one is generated
+	// once and kept in the engine file.
+	EngineID string    `json:"engineID"`
+	Users    []UsmUser `json:"users"`
+}
+
+// UsmUser is an SNMPv3 user. Requests of a user with a privacy protocol
+// must be encrypted, the ones of other users authenticated. Views lists the
+// subtrees the user may read by name or OID, everything if empty.
+type UsmUser struct {
+	Name         string   `json:"name"`
+	AuthProtocol string   `json:"authProtocol"`
+	AuthPassword string   `json:"authPassword"`
+	PrivProtocol string   `json:"privProtocol"`
+	PrivPassword string   `json:"privPassword"`
+	Views        []string `json:"views"`
+}
+
+// LoadSnmpV3Config reads an SnmpV3Config from the JSON file at path.
+func LoadSnmpV3Config(path string) (*SnmpV3Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &SnmpV3Config{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// usmUser is a user with keys localized to the engine.
+type usmUser struct {
+	auth    usmAuthProtocol
+	authKey []byte
+	privKey []byte // nil without privacy
+	views   snmpViews
+}
+
+// usmEngine is the authoritative SNMP engine of the agent. It is not safe
+// for concurrent use, the agent serves one request at a time.
+type usmEngine struct {
+	engineID []byte
+	boots    int64
+	started  time.Time
+	users    map[string]*usmUser
+	salt     uint64
+	stats    [usmStatsDecryptionErrors + 1]uint32
+}
+
+// EnableUSM answers SNMPv3 requests of the users in config. The engine ID
+// and the snmpEngineBoots counter, incremented on every call, are kept in
+// the JSON file at enginePath.
+func (a *SnmpAgent) EnableUSM(config *SnmpV3Config, enginePath string) error {
+	var configured []byte
+	if config.EngineID != "" {
+		var err error
+		configured, err = hex.DecodeString(config.EngineID)
+		if err != nil || len(configured) < 5 || len(configured) > 32 {
+			return fmt.Errorf("invalid engine ID %q, want 5 to 32 hex encoded bytes", config.EngineID)
+		}
+	}
+	engineID, boots, err := bootUsmEngine(enginePath, configured)
+	if err != nil {
+		return err
+	}
+
+	e := &usmEngine{engineID: engineID, boots: boots, started: time.Now(), users: map[string]*usmUser{}}
+	var salt [8]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return err
+	}
+	e.salt = binary.BigEndian.Uint64(salt[:])
+	for _, user := range config.Users {
+		if _, ok := e.users[user.Name]; ok {
+			return fmt.Errorf("duplicate SNMPv3 user %q", user.Name)
+		}
+		u, err := newUsmUser(user, engineID, a.views)
+		if err != nil {
+			return fmt.Errorf("SNMPv3 user %q: %w", user.Name, err)
+		}
+		e.users[user.Name] = u
+	}
+	a.usm = e
+	return nil
+}
+
+// usmEngineState is the content of the engine file.
+type usmEngineState struct {
+	EngineID    string `json:"engineID"`
+	EngineBoots int64  `json:"engineBoots"`
+}
+
+// bootUsmEngine increments the boots counter in the engine file at path and
+// returns it with the engine ID. The configured engine ID replaces the one
+// in the file, restarting the counter, a new one is generated if neither
+// exists.
+func bootUsmEngine(path string, configured []byte) ([]byte, int64, error) {
+	state := usmEngineState{}
+	b, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &state); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, 0, err
+	}
+	engineID, err := hex.DecodeString(state.EngineID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid engine ID in %s: %w", path, err)
+	}
+	if configured != nil && !bytes.Equal(configured, engineID) {
+		engineID, state.EngineBoots = configured, 0
+	}
+	if len(engineID) == 0 {
+		if engineID, err = newUsmEngineID(); err != nil {
+			return nil, 0, err
+		}
+		state.EngineBoots = 0
+	}
+	state.EngineID = hex.EncodeToString(engineID)
+	state.EngineBoots = min(state.EngineBoots+1, usmMaxEngineBoots)
+
+	b, err = json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, 0, err
+	}
+	// replace the file atomically, a lost boots increment would reopen the
+	// time window for replayed messages
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return nil, 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, 0, err
+	}
+	return engineID, state.EngineBoots, nil
+}
+
+// newUsmEngineID returns a random engine ID in the octets format of
+// RFC 3411 under the net-snmp enterprise number, like the rest of BIRD-MIB.
+func newUsmEngineID() ([]byte, error) {
+	engineID := []byte{0x80, 0x00, 0x1f, 0x88, 0x05}
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	return append(engineID, random...), nil
+}
+
+func newUsmUser(user UsmUser, engineID []byte, views snmpViews) (*usmUser, error) {
+	if user.Name == "" || len(user.Name) > 32 {
+		return nil, errors.New("name must be 1 to 32 characters long")
+	}
+	auth, ok := usmAuthProtocols[user.AuthProtocol]
+	if !ok {
+		return nil, fmt.Errorf("unsupported auth protocol %q", user.AuthProtocol)
+	}
+	if len(user.AuthPassword) < 8 {
+		return nil, errors.New("auth password shorter than 8 characters")
+	}
+	u := &usmUser{auth: auth, authKey: auth.localizeKey(user.AuthPassword, engineID)}
+
+	if user.PrivProtocol != "" {
+		keyLen, ok := usmPrivKeyLengths[user.PrivProtocol]
+		if !ok {
+			return nil, fmt.Errorf("unsupported priv protocol %q", user.PrivProtocol)
+		}
+		if len(user.PrivPassword) < 8 {
+			return nil, errors.New("priv password shorter than 8 characters")
+		}
+		u.privKey = auth.extendKey(auth.localizeKey(user.PrivPassword, engineID), keyLen)
+	}
+
+	var err error
+	if u.views, err = restrictViews(views, user.Views); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// localizeKey turns a password into a key localized to the engine,
+// RFC 3414 section A.2.
+func (p usmAuthProtocol) localizeKey(password string, engineID []byte) []byte {
+	h := p.hash()
+	buf := make([]byte, 64)
+	for i := 0; i < 1048576; i += len(buf) {
+		for j := range buf {
+			buf[j] = password[(i+j)%len(password)]
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// extendKey extends a localized key shorter than n bytes by hashing it,
+// the way net-snmp does for AES-192 and AES-256, and truncates it to n.
+func (p usmAuthProtocol) extendKey(key []byte, n int) []byte {
+	for len(key) < n {
+		h := p.hash()
+		h.Write(key)
+		key = h.Sum(key)
+	}
+	return key[:n]
+}
+
+// restrictViews returns the parts of views within the named subtrees,
+// views itself without names.
+func restrictViews(views snmpViews, names []string) (snmpViews, error) {
+	if len(names) == 0 {
+		return views, nil
+	}
+	restricted := snmpViews{}
+	for _, name := range names {
+		subtree, ok := snmpViewNames[name]
+		if !ok {
+			oid, err := value.ParseOID(name)
+			if err != nil {
+				return nil, fmt.Errorf("unknown view %q", name)
+			}
+			subtree = oid
+		}
+		for _, view := range views {
+			switch {
+			case oidHasPrefix(view.subtree, subtree):
+				restricted = append(restricted, view)
+			case oidHasPrefix(subtree, view.subtree):
+				end := append(value.OID{}, subtree...)
+				end[len(end)-1]++
+				restricted = append(restricted, snmpView{subtree: subtree, end: end, handler: view.handler})
+			}
+		}
+	}
+	sort.Slice(restricted, func(i int, j int) bool {
+		return compareOids(restricted[i].subtree, restricted[j].subtree) == -1
+	})
+	// drop views within others
+	merged := snmpViews{}
+	for _, view := range restricted {
+		if len(merged) > 0 && oidHasPrefix(view.subtree, merged[len(merged)-1].subtree) {
+			continue
+		}
+		merged = append(merged, view)
+	}
+	return merged, nil
+}
+
+// mac returns the truncated HMAC of a message.
+func (u *usmUser) mac(message []byte) []byte {
+	h := hmac.New(u.auth.hash, u.authKey)
+	h.Write(message)
+	return h.Sum(nil)[:u.auth.macLen]
+}
+
+// crypt encrypts or decrypts a scoped pdu with AES in CFB mode, the IV is
+// made of the engine boots and time of the message and the salt,
+// RFC 3826 section 3.1.
+func (u *usmUser) crypt(b []byte, boots int64, engineTime int64, salt []byte, encrypt bool) []byte {
+	block, err := aes.NewCipher(u.privKey)
+	if err != nil {
+		// the key length is one of the AES ones
+		panic(err)
+	}
+	iv := make([]byte, 0, aes.BlockSize)
+	iv = binary.BigEndian.AppendUint32(iv, uint32(boots))
+	iv = binary.BigEndian.AppendUint32(iv, uint32(engineTime))
+	iv = append(iv, salt...)
+
+	out := make([]byte, len(b))
+	if encrypt {
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(out, b)
+	} else {
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(out, b)
+	}
+	return out
+}
+
+// usmWire is a decoded SNMPv3 message, the byte slices point into it.
+type usmWire struct {
+	msgID         int64
+	maxSize       int64
+	flags         byte
+	securityModel int64
+
+	engineID   []byte
+	boots      int64
+	time       int64
+	userName   []byte
+	authParams []byte
+	privParams []byte
+
+	dataTag byte // berSequence for a plaintext scoped pdu
+	data    []byte
+}
+
+func decodeUsmMessage(packet []byte) (*usmWire, error) {
+	w := &usmWire{}
+	d := berDecoder{b: packet}
+	d.b = d.read(berSequence)
+	if version := d.int(); d.err == nil && version != snmpVersion3 {
+		return nil, fmt.Errorf("unexpected SNMP version %d", version)
+	}
+	header := berDecoder{}
+	header.b = d.read(berSequence)
+	header.err = d.err
+	w.msgID = header.int()
+	w.maxSize = header.int()
+	flags := header.read(berOctetString)
+	w.securityModel = header.int()
+	if header.err != nil {
+		return nil, header.err
+	}
+	if len(flags) != 1 {
+		return nil, errors.New("invalid msgFlags")
+	}
+	w.flags = flags[0]
+
+	securityParameters := d.read(berOctetString)
+	if d.err != nil {
+		return nil, d.err
+	}
+	var err error
+	w.dataTag, w.data, _, err = berRead(d.b)
+	if err != nil {
+		return nil, err
+	}
+
+	params := berDecoder{b: securityParameters}
+	params.b = params.read(berSequence)
+	w.engineID = params.read(berOctetString)
+	w.boots = params.int()
+	w.time = params.int()
+	w.userName = params.read(berOctetString)
+	w.authParams = params.read(berOctetString)
+	w.privParams = params.read(berOctetString)
+	return w, params.err
+}
+
+// usmMessage is the state of an SNMPv3 request needed for its response.
+type usmMessage struct {
+	engine      *usmEngine
+	msgID       int64
+	flags       byte     // security level, and reportable for requests
+	user        *usmUser // nil unless authenticated
+	userName    []byte
+	contextName []byte
+}
+
+// engineTime returns snmpEngineTime, the seconds since the agent started.
+func (e *usmEngine) engineTime() int64 {
+	return int64(time.Since(e.started) / time.Second)
+}
+
+// parse decodes and authenticates an SNMPv3 request. Requests failing a
+// check are answered with the report or error response returned instead
+// of the request, or dropped if that is nil.
+func (e *usmEngine) parse(packet []byte) (*snmpMessage, []byte) {
+	w, err := decodeUsmMessage(packet)
+	if err != nil || w.securityModel != usmSecurityModel || w.maxSize < usmMinMessageSize ||
+		w.flags&(usmFlagAuth|usmFlagPriv) == usmFlagPriv {
+		return nil, nil
+	}
+	m := &usmMessage{engine: e, msgID: w.msgID, userName: w.userName}
+	msg := &snmpMessage{version: snmpVersion3, usm: m}
+	reportable := w.flags&usmFlagReportable != 0
+	if w.dataTag == berSequence {
+		// for the request id of reports
+		parseScopedPDU(msg, w.data)
+	}
+
+	if !bytes.Equal(w.engineID, e.engineID) {
+		return nil, e.report(msg, reportable, usmStatsUnknownEngineIDs, 0)
+	}
+	user := e.users[string(w.userName)]
+	if user == nil {
+		return nil, e.report(msg, reportable, usmStatsUnknownUserNames, 0)
+	}
+	if w.flags&usmFlagPriv != 0 && user.privKey == nil {
+		return nil, e.report(msg, reportable, usmStatsUnsupportedSecLevels, 0)
+	}
+	if w.flags&usmFlagAuth != 0 {
+		if len(w.authParams) != user.auth.macLen || !hmac.Equal(w.authParams, user.mac(zeroAuthParams(packet))) {
+			return nil, e.report(msg, reportable, usmStatsWrongDigests, 0)
+		}
+		m.user = user
+		engineTime := e.engineTime()
+		if e.boots == usmMaxEngineBoots || w.boots != e.boots || w.time < engineTime-usmTimeWindow || w.time > engineTime+usmTimeWindow {
+			return nil, e.report(msg, reportable, usmStatsNotInTimeWindows, usmFlagAuth)
+		}
+	}
+
+	data := w.data
+	if w.flags&usmFlagPriv != 0 {
+		if w.dataTag != berOctetString || len(w.privParams) != 8 {
+			return nil, e.report(msg, reportable, usmStatsDecryptionErrors, 0)
+		}
+		data, _, err = berExpect(user.crypt(w.data, w.boots, w.time, w.privParams, false), berSequence)
+		if err != nil {
+			return nil, e.report(msg, reportable, usmStatsDecryptionErrors, 0)
+		}
+	} else if w.dataTag != berSequence {
+		return nil, nil
+	}
+	if err := parseScopedPDU(msg, data); err != nil {
+		return nil, nil
+	}
+
+	m.flags = w.flags & (usmFlagAuth | usmFlagPriv)
+	msg.limit = int(min(w.maxSize, snmpMaxMessageSize)) - usmMessageOverhead - 2*len(e.engineID) - len(m.userName) - len(m.contextName)
+	msg.views = user.views
+	// users with privacy are served encrypted requests only, the others
+	// authenticated ones
+	if m.flags&usmFlagAuth == 0 || (user.privKey != nil && m.flags&usmFlagPriv == 0) {
+		return nil, msg.errorResponse(snmpAuthorizationError, 0)
+	}
+	return msg, nil
+}
+
+// zeroAuthParams returns a copy of a message with the authentication
+// parameters zeroed, the input of the HMAC.
+func zeroAuthParams(packet []byte) []byte {
+	b := append([]byte{}, packet...)
+	if w, err := decodeUsmMessage(b); err == nil {
+		clear(w.authParams)
+	}
+	return b
+}
+
+// parseScopedPDU decodes the content of a plaintext scoped pdu into msg.
+func parseScopedPDU(msg *snmpMessage, b []byte) error {
+	d := berDecoder{b: b}
+	d.read(berOctetString) // contextEngineID
+	contextName := d.read(berOctetString)
+	if d.err != nil {
+		return d.err
+	}
+	msg.usm.contextName = contextName
+	msg.varBinds = nil
+	return parseSnmpPDU(msg, d.b)
+}
+
+// report counts a failed check and returns the report for it, nil unless
+// the request is reportable. Reports are sent with the flags security
+// level.
+func (e *usmEngine) report(msg *snmpMessage, reportable bool, stat int, flags byte) []byte {
+	e.stats[stat]++
+	if !reportable {
+		return nil
+	}
+	msg.usm.flags = flags
+	oid := append(append(value.OID{}, oidUsmStats...), uint32(stat), 0)
+	varBind, _ := berVarBind(oid, pdu.VariableTypeCounter32, e.stats[stat])
+	return msg.usm.encode(encodeSnmpPDU(snmpReport, msg.requestID, snmpNoError, 0, varBind))
+}
+
+// encode wraps a pdu into an SNMPv3 message of the engine with the
+// security level of m, encrypting and authenticating it as required.
+func (m *usmMessage) encode(b []byte) []byte {
+	e := m.engine
+	boots, engineTime := e.boots, e.engineTime()
+	scoped := berTLV(berOctetString, e.engineID)
+	scoped = append(scoped, berTLV(berOctetString, m.contextName)...)
+	data := berTLV(berSequence, append(scoped, b...))
+
+	var authParams, privParams []byte
+	if m.flags&usmFlagAuth != 0 {
+		authParams = make([]byte, m.user.auth.macLen)
+	}
+	if m.flags&usmFlagPriv != 0 {
+		e.salt++
+		privParams = binary.BigEndian.AppendUint64(nil, e.salt)
+		data = berTLV(berOctetString, m.user.crypt(data, boots, engineTime, privParams, true))
+	}
+
+	params := berTLV(berOctetString, e.engineID)
+	params = append(params, berTLV(berInteger, berInt(boots))...)
+	params = append(params, berTLV(berInteger, berInt(engineTime))...)
+	params = append(params, berTLV(berOctetString, m.userName)...)
+	params = append(params, berTLV(berOctetString, authParams)...)
+	params = append(params, berTLV(berOctetString, privParams)...)
+
+	header := berTLV(berInteger, berInt(m.msgID))
+	header = append(header, berTLV(berInteger, berInt(snmpMaxMessageSize))...)
+	header = append(header, berTLV(berOctetString, []byte{m.flags})...)
+	header = append(header, berTLV(berInteger, berInt(usmSecurityModel))...)
+
+	msg := berTLV(berInteger, berInt(snmpVersion3))
+	msg = append(msg, berTLV(berSequence, header)...)
+	msg = append(msg, berTLV(berOctetString, berTLV(berSequence, params))...)
+	msg = berTLV(berSequence, append(msg, data...))
+	if authParams != nil {
+		mac := m.user.mac(msg)
+		w, _ := decodeUsmMessage(msg)
+		copy(w.authParams, mac)
+	}
+	return msg
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func Test_localizeKey(t *testing.T) {
+	// RFC 3414 section A.3.2
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	got := hex.EncodeToString(usmAuthProtocols["SHA"].localizeKey("maplesyrup", engineID))
+	if want := "6695febc9288e36282235fc7151f128497b38f3f"; got != want {
+		t.Errorf("localizeKey() = %s, want %s", got, want)
+	}
+}
+
+func Test_bootUsmEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.json")
+	engineID, boots, err := bootUsmEngine(path, nil)
+	if err != nil || len(engineID) != 17 || boots != 1 {
+		t.Fatalf("bootUsmEngine() = %x, %d, %v, want a new engine ID booted once", engineID, boots, err)
+	}
+	again, boots, err := bootUsmEngine(path, nil)
+	if err != nil || !reflect.DeepEqual(again, engineID) || boots != 2 {
+		t.Errorf("bootUsmEngine() = %x, %d, %v, want %x booted twice", again, boots, err, engineID)
+	}
+	configured := []byte{0x80, 0, 0x1f, 0x88, 4, 'b', 'g', 'p'}
+	again, boots, err = bootUsmEngine(path, configured)
+	if err != nil || !reflect.DeepEqual(again, configured) || boots != 1 {
+		t.Errorf("bootUsmEngine() = %x, %d, %v, want %x booted once", again, boots, err, configured)
+	}
+}
+
+// usmTestRequest encodes a request of a user with the engine ID, boots and
+// time of e.
+func usmTestRequest(e usmEngine, user *usmUser, userName string, flags byte, oids ...value.OID) []byte {
+	var varBinds []byte
+	for _, oid := range oids {
+		varBind, _ := berVarBind(oid, pdu.VariableTypeNull, nil)
+		varBinds = append(varBinds, varBind...)
+	}
+	m := &usmMessage{engine: &e, msgID: 7, flags: flags | usmFlagReportable, user: user, userName: []byte(userName)}
+	return m.encode(encodeSnmpPDU(snmpGetRequest, 42, 0, 0, varBinds))
+}
+
+// usmTestResponse checks and decrypts a response for user.
+func usmTestResponse(t *testing.T, b []byte, user *usmUser) (*usmWire, byte, snmpTestResponse) {
+	t.Helper()
+	if b == nil {
+		t.Fatal("no response")
+	}
+	w, err := decodeUsmMessage(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := w.data
+	if w.flags&usmFlagAuth != 0 && !reflect.DeepEqual(w.authParams, user.mac(zeroAuthParams(b))) {
+		t.Fatal("wrong digest of the response")
+	}
+	if w.flags&usmFlagPriv != 0 {
+		if data, _, err = berExpect(user.crypt(w.data, w.boots, w.time, w.privParams, false), berSequence); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := berDecoder{b: data}
+	d.read(berOctetString)
+	d.read(berOctetString)
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	pduType, response := parseSnmpTestPDU(t, d.b)
+	return w, pduType, response
+}
+
+func TestSnmpAgentUSM(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	agent, err := NewSnmpAgent("127.0.0.1:0", "", h, DefaultSubtrees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &SnmpV3Config{Users: []UsmUser{
+		{Name: "nms", AuthProtocol: "SHA-256", AuthPassword: "authpassword", PrivProtocol: "AES", PrivPassword: "privpassword"},
+		{Name: "peering", AuthProtocol: "SHA", AuthPassword: "authpassword", Views: []string{"bgp4"}},
+	}}
+	if err := agent.EnableUSM(config, filepath.Join(t.TempDir(), "engine.json")); err != nil {
+		t.Fatal(err)
+	}
+	// the requester's copy of the engine
+	engine := *agent.usm
+	nms, peering := engine.users["nms"], engine.users["peering"]
+	wrongPassword, err := newUsmUser(UsmUser{Name: "nms", AuthProtocol: "SHA-256", AuthPassword: "wrongpassword"}, engine.engineID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peeringPriv, err := newUsmUser(UsmUser{Name: "peering", AuthProtocol: "SHA", AuthPassword: "authpassword", PrivProtocol: "AES", PrivPassword: "privpassword"}, engine.engineID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.Serve(ctx)
+	conn, err := net.Dial("udp", agent.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if response := snmpTestExchange(t, conn, snmpTestRequest(snmpVersion2c, "", snmpGetRequest, 0, 0, oidSysDescr)); response != nil {
+		t.Error("answered an SNMPv2c request without a community configured")
+	}
+
+	t.Run("discovery", func(t *testing.T) {
+		unknown := usmEngine{started: time.Now()}
+		w, pduType, got := usmTestResponse(t, snmpTestExchange(t, conn, usmTestRequest(unknown, nil, "", 0)), nil)
+		if pduType != snmpReport || len(got.varBinds) != 1 || got.varBinds[0].oid.String() != "1.3.6.1.6.3.15.1.1.4.0" {
+			t.Fatalf("response = 0x%02x %+v, want an usmStatsUnknownEngineIDs report", pduType, got)
+		}
+		if !reflect.DeepEqual(w.engineID, engine.engineID) || w.boots != 1 {
+			t.Errorf("reported engine = %x/%d, want %x/1", w.engineID, w.boots, engine.engineID)
+		}
+	})
+
+	localAs := append(append(value.OID{}, oidBgpLocalAs...), 0)
+	tests := []struct {
+		name       string
+		engine     usmEngine
+		user       *usmUser
+		userName   string
+		flags      byte
+		oids       []value.OID
+		wantReport string
+		wantStatus int64
+		wantTags   []byte
+	}{
+		{name: "auth priv", engine: engine, user: nms, userName: "nms", flags: usmFlagAuth | usmFlagPriv, oids: []value.OID{oidSysDescr, localAs}, wantTags: []byte{berOctetString, berInteger}},
+		{name: "view", engine: engine, user: peering, userName: "peering", flags: usmFlagAuth, oids: []value.OID{oidSysDescr, localAs}, wantTags: []byte{byte(pdu.VariableTypeNoSuchObject), berInteger}},
+		{name: "unencrypted request of a priv user", engine: engine, user: nms, userName: "nms", flags: usmFlagAuth, oids: []value.OID{localAs}, wantStatus: snmpAuthorizationError, wantTags: []byte{berNull}},
+		{name: "unknown user", engine: engine, userName: "guest", oids: []value.OID{localAs}, wantReport: "1.3.6.1.6.3.15.1.1.3.0"},
+		{name: "wrong password", engine: engine, user: wrongPassword, userName: "nms", flags: usmFlagAuth, oids: []value.OID{localAs}, wantReport: "1.3.6.1.6.3.15.1.1.5.0"},
+		{name: "priv without priv protocol", engine: engine, user: peeringPriv, userName: "peering", flags: usmFlagAuth | usmFlagPriv, oids: []value.OID{localAs}, wantReport: "1.3.6.1.6.3.15.1.1.1.0"},
+	}
+	stale := engine
+	stale.boots = 5
+	tests = append(tests, struct {
+		name       string
+		engine     usmEngine
+		user       *usmUser
+		userName   string
+		flags      byte
+		oids       []value.OID
+		wantReport string
+		wantStatus int64
+		wantTags   []byte
+	}{name: "not in time window", engine: stale, user: nms, userName: "nms", flags: usmFlagAuth | usmFlagPriv, oids: []value.OID{localAs}, wantReport: "1.3.6.1.6.3.15.1.1.2.0"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			if tt.wantReport != "" {
+				// reports are authenticated at most
+				user = nms
+			}
+			_, pduType, got := usmTestResponse(t, snmpTestExchange(t, conn, usmTestRequest(tt.engine, tt.user, tt.userName, tt.flags, tt.oids...)), user)
+			if tt.wantReport != "" {
+				if pduType != snmpReport || len(got.varBinds) != 1 || got.varBinds[0].oid.String() != tt.wantReport {
+					t.Errorf("response = 0x%02x %+v, want a %s report", pduType, got, tt.wantReport)
+				}
+				return
+			}
+			if pduType != snmpResponse || got.status != tt.wantStatus {
+				t.Fatalf("response = 0x%02x %+v, want status %d", pduType, got, tt.wantStatus)
+			}
+			var gotTags []byte
+			for _, varBind := range got.varBinds {
+				gotTags = append(gotTags, varBind.tag)
+			}
+			if !reflect.DeepEqual(gotTags, tt.wantTags) {
+				t.Errorf("value tags = %x, want %x", gotTags, tt.wantTags)
+			}
+		})
+	}
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"encoding/binary"
 	"net"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/posteo/go-agentx/value"
 )
 
+// InetAddressType values from INET-ADDRESS-MIB (RFC 4001).
+const (
+	inetAddressTypeUnknown int32 = 0
+	inetAddressTypeIPv4    int32 = 1
+	inetAddressTypeIPv6    int32 = 2
+	inetAddressTypeIPv6z   int32 = 4
+)
+
 func ipToOid(ip net.IP) []uint32 {
 	ret := []uint32{}
 	for _, x := range ip.To4() {
@@ -14,6 +26,48 @@ func ipToOid(ip net.IP) []uint32 {
 	return ret
 }
 
+// inetAddress returns the InetAddressType and InetAddress octets for ip.
+// Link-local IPv6 addresses are encoded as ipv6z with the zone index of
+// the interface appended, so peers on different links stay distinct.
+func inetAddress(ip net.IP, zone string) (int32, []byte) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return inetAddressTypeIPv4, []byte(ip4)
+	}
+	ip6 := ip.To16()
+	if ip6 == nil {
+		return inetAddressTypeUnknown, nil
+	}
+	if ip6.IsLinkLocalUnicast() {
+		addr := append([]byte{}, ip6...)
+		return inetAddressTypeIPv6z, binary.BigEndian.AppendUint32(addr, zoneIndex(zone))
+	}
+	return inetAddressTypeIPv6, []byte(ip6)
+}
+
+// zoneIndex resolves an interface name (or a numeric zone) to its index.
+func zoneIndex(zone string) uint32 {
+	if zone == "" {
+		return 0
+	}
+	if n, err := strconv.ParseUint(zone, 10, 32); err == nil {
+		return uint32(n)
+	}
+	if iface, err := net.InterfaceByName(zone); err == nil {
+		return uint32(iface.Index)
+	}
+	return 0
+}
+
+// inetAddressToOid encodes an InetAddressType/InetAddress pair as a table
+// index: the type, the address length and then one subidentifier per octet.
+func inetAddressToOid(addrType int32, addr []byte) []uint32 {
+	ret := []uint32{uint32(addrType), uint32(len(addr))}
+	for _, x := range addr {
+		ret = append(ret, uint32(x))
+	}
+	return ret
+}
+
 // Compare returns an integer comparing two SNMP OIDs lexicographically.
 // The result will be :
 //
@@ -38,3 +92,57 @@ func compareOids(x value.OID, y value.OID) int {
 	}
 	return 0
 }
+
+// oidHasPrefix tells whether oid is within the subtree prefix.
+func oidHasPrefix(oid value.OID, prefix value.OID) bool {
+	if len(oid) < len(prefix) {
+		return false
+	}
+	return compareOids(oid[:len(prefix)], prefix) == 0
+}
+
+// ipv4OrZero returns ip as a 4 byte address, 0.0.0.0 if it is not IPv4.
+func ipv4OrZero(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return net.IPv4zero.To4()
+}
+
+// stringToOid encodes s as a table index: its length and then one
+// subidentifier per byte.
+func stringToOid(s string) []uint32 {
+	ret := []uint32{uint32(len(s))}
+	for _, x := range []byte(s) {
+		ret = append(ret, uint32(x))
+	}
+	return ret
+}
+
+// dateAndTime encodes t as an SNMPv2-TC DateAndTime including its offset
+// from UTC. The zero time is encoded as nil.
+func dateAndTime(t time.Time) []byte {
+	if t.IsZero() {
+		return nil
+	}
+	_, offset := t.Zone()
+	direction := byte('+')
+	if offset < 0 {
+		direction = '-'
+		offset = -offset
+	}
+	ret := binary.BigEndian.AppendUint16(nil, uint16(t.Year()))
+	return append(ret,
+		byte(t.Month()), byte(t.Day()), byte(t.Hour()), byte(t.Minute()), byte(t.Second()),
+		byte(t.Nanosecond()/100000000), direction, byte(offset/3600), byte(offset%3600/60))
+}
+
+// sortedChannelNames returns the names of the channels of proto in order.
+func sortedChannelNames(proto ProtocolBGPStatus) []string {
+	names := make([]string, 0, len(proto.Channels))
+	for name := range proto.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
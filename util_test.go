@@ -1,7 +1,10 @@
 package main
 
 import (
+	"net"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/posteo/go-agentx/value"
 )
@@ -31,3 +34,44 @@ func Test_compareOids(t *testing.T) {
 		})
 	}
 }
+
+func Test_inetAddressToOid(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   net.IP
+		zone string
+		want []uint32
+	}{
+		{name: "ipv4", ip: net.ParseIP("192.168.32.1"), want: []uint32{1, 4, 192, 168, 32, 1}},
+		{name: "ipv6", ip: net.ParseIP("2001:db8::1"), want: []uint32{2, 16, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{name: "ipv6 link-local", ip: net.ParseIP("fe80::1"), zone: "7", want: []uint32{4, 20, 0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 7}},
+		{name: "ipv6 link-local unknown zone", ip: net.ParseIP("fe80::1"), zone: "nonexistent0", want: []uint32{4, 20, 0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inetAddressToOid(inetAddress(tt.ip, tt.zone)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inetAddressToOid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dateAndTime(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want []byte
+	}{
+		{name: "zero", t: time.Time{}, want: nil},
+		{name: "utc", t: time.Date(2024, 10, 12, 20, 41, 10, 197000000, time.UTC), want: []byte{0x07, 0xe8, 10, 12, 20, 41, 10, 1, '+', 0, 0}},
+		{name: "east", t: time.Date(2024, 10, 12, 20, 41, 10, 0, time.FixedZone("", 5*3600+30*60)), want: []byte{0x07, 0xe8, 10, 12, 20, 41, 10, 0, '+', 5, 30}},
+		{name: "west", t: time.Date(2024, 10, 12, 20, 41, 10, 0, time.FixedZone("", -7*3600)), want: []byte{0x07, 0xe8, 10, 12, 20, 41, 10, 0, '-', 7, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dateAndTime(tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dateAndTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
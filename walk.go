@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+// WalkCmd prints the tree served over SNMP without an agentx master, to
+// check what bird2snmp makes of bird's output.
+type WalkCmd struct {
+	OID     string `arg:"" optional:"" help:"subtree to print by OID or MIB object name, everything if empty"`
+	Numeric bool   `short:"n" help:"print numeric OIDs instead of MIB object names"`
+}
+
+func (c *WalkCmd) Run(ctx context.Context) error {
+	names, err := LoadBundledMibNames()
+	if err != nil {
+		return err
+	}
+	root, err := names.Parse(c.OID)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := refreshOnce(ctx)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(os.Stdout)
+	walkSnapshot(w, snapshot, root, names, c.Numeric)
+	return w.Flush()
+}
+
+// refreshOnce connects to bird and returns the snapshot of a single
+// refresh, for commands inspecting bird's state once.
+func refreshOnce(ctx context.Context) (*birdSnapshot, error) {
+	h := &BirdBGPHandler{
+		conn:        NewBirdConn(CLI.BirdSock, CLI.BirdTimeout),
+		started:     time.Now(),
+		StalePolicy: StalePolicyFlag,
+	}
+	defer h.conn.Close()
+	if err := h.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to refresh BGP data: %w", err)
+	}
+	return h.current(), nil
+}
+
+// walkSnapshot writes the objects of snapshot within root, one per line in
+// the style of snmpwalk.
+func walkSnapshot(w io.Writer, snapshot *birdSnapshot, root value.OID, names *MibNames, numeric bool) {
+	end := value.OID{3} // beyond iso(1) and joint-iso-itu-t(2)
+	if len(root) > 0 {
+		end = append(value.OID{}, root...)
+		end[len(end)-1]++
+	}
+	snapshot.data.Walk(root, true, end, func(oid value.OID, t pdu.VariableType, v interface{}) bool {
+		name := oid.String()
+		if !numeric {
+			name = names.Name(oid)
+		}
+		fmt.Fprintf(w, "%s = %s\n", name, formatSnmpValue(t, v))
+		return true
+	})
+}
+
+// formatSnmpValue formats a value of type t like snmpwalk does.
+func formatSnmpValue(t pdu.VariableType, v interface{}) string {
+	switch t {
+	case pdu.VariableTypeInteger:
+		return fmt.Sprintf("INTEGER: %v", v)
+	case pdu.VariableTypeOctetString:
+		var b []byte
+		switch v := v.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		}
+		if printable(b) {
+			return fmt.Sprintf("STRING: %q", b)
+		}
+		return "Hex-STRING: " + hexString(b)
+	case pdu.VariableTypeObjectIdentifier:
+		return fmt.Sprintf("OID: %v", v)
+	case pdu.VariableTypeIPAddress:
+		if ip, ok := v.(net.IP); ok && ip.To4() != nil {
+			return "IpAddress: " + ip.String()
+		}
+		return "IpAddress: 0.0.0.0"
+	case pdu.VariableTypeCounter32:
+		return fmt.Sprintf("Counter32: %v", v)
+	case pdu.VariableTypeGauge32:
+		return fmt.Sprintf("Gauge32: %v", v)
+	case pdu.VariableTypeTimeTicks:
+		if d, ok := v.(time.Duration); ok {
+			return fmt.Sprintf("Timeticks: (%d) %s", uint32(d/(10*time.Millisecond)), d.Truncate(10*time.Millisecond))
+		}
+	case pdu.VariableTypeOpaque:
+		if b, ok := v.([]byte); ok {
+			return "Opaque: " + hexString(b)
+		}
+	case pdu.VariableTypeCounter64:
+		return fmt.Sprintf("Counter64: %v", v)
+	case pdu.VariableTypeNull:
+		return "NULL"
+	}
+	return fmt.Sprintf("%s: %v", t, v)
+}
+
+func printable(b []byte) bool {
+	for _, r := range string(b) {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) && r != '\t' && r != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+func hexString(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/posteo/go-agentx/pdu"
+	"github.com/posteo/go-agentx/value"
+)
+
+func TestMibNames(t *testing.T) {
+	names, err := LoadBundledMibNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		oid  value.OID
+		want string
+	}{
+		{oid: oidBgp, want: "bgp"},
+		{oid: append(append(value.OID{}, oidBgpPeerState...), 192, 168, 32, 1), want: "bgpPeerState.192.168.32.1"},
+		{oid: append(append(value.OID{}, oidBgpLocalAs...), 0), want: "bgpLocalAs.0"},
+		{oid: append(append(value.OID{}, oidBirdDaemonState...), 0), want: "birdDaemonState.0"},
+		{oid: oidBird, want: "birdMIB"},
+		{oid: value.OID{1, 3, 6, 1, 4, 1, 9}, want: "1.3.6.1.4.1.9"},
+	}
+	for _, tt := range tests {
+		if got := names.Name(tt.oid); got != tt.want {
+			t.Errorf("Name(%v) = %s, want %s", tt.oid, got, tt.want)
+		}
+	}
+
+	for s, want := range map[string]value.OID{
+		"":                 {},
+		"bgpPeerTable":     testBgpPeerTable,
+		".1.3.6.1.2.1.15":  oidBgp,
+		"1.3.6.1.2.1.15.3": testBgpPeerTable,
+	} {
+		if got, err := names.Parse(s); err != nil || compareOids(got, want) != 0 {
+			t.Errorf("Parse(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := names.Parse("bgpNoSuchObject"); err == nil {
+		t.Error("Parse() succeeded for an unknown object")
+	}
+}
+
+var testBgpPeerTable = value.OID{1, 3, 6, 1, 2, 1, 15, 3}
+
+func Test_walkSnapshot(t *testing.T) {
+	h := newTestHandler(time.Now(), StalePolicyFlag)
+	names, err := LoadBundledMibNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	walkSnapshot(&b, h.current(), testBgpPeerTable, names, false)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if want := "bgpPeerIdentifier.192.168.32.1 = IpAddress: 192.168.32.1"; lines[0] != want {
+		t.Errorf("first line = %s, want %s", lines[0], want)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "bgpPeer") {
+			t.Errorf("%s is not within bgpPeerTable", line)
+		}
+	}
+
+	b.Reset()
+	walkSnapshot(&b, h.current(), append(append(value.OID{}, oidBgpLocalAs...), 0), names, true)
+	if got, want := b.String(), "1.3.6.1.2.1.15.2.0 = INTEGER: 64846\n"; got != want {
+		t.Errorf("walkSnapshot() = %q, want %q", got, want)
+	}
+}
+
+func Test_formatSnmpValue(t *testing.T) {
+	tests := []struct {
+		t    pdu.VariableType
+		v    interface{}
+		want string
+	}{
+		{pdu.VariableTypeOctetString, "ipv4", `STRING: "ipv4"`},
+		{pdu.VariableTypeOctetString, []byte{7, 232, 10}, "Hex-STRING: 07 E8 0A"},
+		{pdu.VariableTypeTimeTicks, 90*time.Second + 5*time.Millisecond, "Timeticks: (9000) 1m30s"},
+		{pdu.VariableTypeCounter64, uint64(459), "Counter64: 459"},
+		{pdu.VariableTypeObjectIdentifier, "1.3.6.1.2.1.15", "OID: 1.3.6.1.2.1.15"},
+	}
+	for _, tt := range tests {
+		if got := formatSnmpValue(tt.t, tt.v); got != tt.want {
+			t.Errorf("formatSnmpValue(%v, %v) = %s, want %s", tt.t, tt.v, got, tt.want)
+		}
+	}
+}